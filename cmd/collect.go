@@ -22,12 +22,14 @@ var collectCmd = &cobra.Command{
 
 var collectTail bool
 var collectSince string
+var collectUntil string
 
 func init() {
 	sinceDefault := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
 
 	collectCmd.Flags().BoolVarP(&collectTail, "follow", "f", false, "Follow output")
 	collectCmd.Flags().StringVarP(&collectSince, "since", "S", sinceDefault, "Specific date from")
+	collectCmd.Flags().StringVarP(&collectUntil, "until", "U", "", "Specific date to")
 
 	rootCmd.AddCommand(collectCmd)
 }
@@ -47,6 +49,14 @@ func runCollect(cmd *cobra.Command, args []string) error {
 			return err
 		}
 		opts.Since = collectSince
+
+		if collectUntil != "" {
+			collectUntil, err := time.Parse(time.RFC3339, collectUntil)
+			if err != nil {
+				return err
+			}
+			opts.Until = collectUntil
+		}
 	}
 
 	events, err := datasource.Collect(cx, config, name, opts)
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -33,6 +34,9 @@ var listenPort uint16
 var config *config_.Config
 var rootcx context.Context
 
+// Background goroutines which finish after rootcx is done.
+var rootwg = &sync.WaitGroup{}
+
 func initStdin(cx context.Context, wg *sync.WaitGroup) (*scrollbuffer.ScrollBuffer, error) {
 	tmpdir := filepath.Join(os.TempDir(), "seigo")
 	if err := os.MkdirAll(tmpdir, 0o777); err != nil {
@@ -85,6 +89,15 @@ func initStdin(cx context.Context, wg *sync.WaitGroup) (*scrollbuffer.ScrollBuff
 	return buf, nil
 }
 
+func initReceivers(cx context.Context, wg *sync.WaitGroup) (*datasource.Receivers, error) {
+	tmpdir := filepath.Join(os.TempDir(), "seigo")
+	if err := os.MkdirAll(tmpdir, 0o777); err != nil {
+		return nil, err
+	}
+
+	return datasource.StartReceivers(cx, config, tmpdir, wg)
+}
+
 func init() {
 	defaultConfigPath, found := os.LookupEnv("SEIGO_CONFIG")
 	if !found {
@@ -110,21 +123,33 @@ func init() {
 	}
 
 	var stdin bool
+	var stdinParser string
+	var stdinPattern string
+	var stdinNonJson string
 
 	flags := rootCmd.PersistentFlags()
 	flags.StringVarP(&listenAddr, "listen-addr", "l", defaultListenAddr, "Listen Address.")
 	flags.Uint16VarP(&listenPort, "port", "p", defaultListenPort, "Listen Port.")
 	flags.StringVarP(&configPath, "config", "C", defaultConfigPath, "Config file path.")
 	flags.BoolVarP(&stdin, "stdin", "s", false, "Read logs from stdin mode. If this flag is specified, --config is ignored.")
+	flags.StringVar(&stdinParser, "parser", "json", "Parser for stdin mode. (json, logfmt, kv, grok, regex, nginx, apache, haproxy, postgres)")
+	flags.StringVar(&stdinPattern, "pattern", "", "Pattern for --parser=grok or regex.")
+	flags.StringVar(&stdinNonJson, "non-json", "drop", "What to do with lines which cannot be parsed in stdin mode. (drop, wrap, error)")
 
-	wg := &sync.WaitGroup{}
 	var cancel context.CancelFunc
 
 	cobra.OnInitialize(func() {
 		rootcx, cancel = context.WithCancel(context.Background())
 
 		if stdin {
-			buf, err := initStdin(rootcx, wg)
+			buf, err := initStdin(rootcx, rootwg)
+			cobra.CheckErr(err)
+
+			opts, err := json.Marshal(map[string]string{
+				"parser":   stdinParser,
+				"pattern":  stdinPattern,
+				"non-json": stdinNonJson,
+			})
 			cobra.CheckErr(err)
 
 			rootcx = context.WithValue(rootcx, datasource.ContextStdinBufKey, buf)
@@ -134,6 +159,7 @@ func init() {
 					{
 						Name: "default",
 						Type: "stdin",
+						Opts: opts,
 					},
 				},
 			}
@@ -148,7 +174,7 @@ func init() {
 
 	cobra.OnFinalize(func() {
 		cancel()
-		wg.Wait()
+		rootwg.Wait()
 	})
 }
 
@@ -156,6 +182,13 @@ func runRoot(cmd *cobra.Command, args []string) error {
 	cx, cancel := signal.NotifyContext(rootcx, os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	// Only while serving. Receivers listen on their own ports.
+	receivers, err := initReceivers(cx, rootwg)
+	if err != nil {
+		return err
+	}
+	cx = context.WithValue(cx, datasource.ContextReceiversKey, receivers)
+
 	addr := fmt.Sprintf("%s:%d", listenAddr, listenPort)
 	return app.Serve(cx, config, addr)
 }
@@ -7,10 +7,15 @@ require (
 	github.com/edsrzf/mmap-go v1.2.0
 	github.com/fsnotify/fsnotify v1.9.0
 	github.com/joho/godotenv v1.5.1
+	github.com/klauspost/compress v1.18.0
 	github.com/labstack/echo/v4 v4.13.4
+	github.com/pierrec/lz4/v4 v4.1.22
 	github.com/spf13/cobra v1.10.1
+	github.com/ulikunitz/xz v0.5.12
 	golang.org/x/crypto v0.43.0
 	golang.org/x/net v0.46.0
+	google.golang.org/protobuf v1.36.9
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -14,17 +14,10 @@ import (
 	"github.com/ysuzuki-bysystems/seigo/internal/web"
 )
 
-func Serve(cx context.Context, cfg *config.Config, addr string) error {
-	wg := &sync.WaitGroup{}
-	defer wg.Wait()
-
+func newEcho(cfg *config.Config) *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
 
-	e.Server.BaseContext = func(l net.Listener) context.Context {
-		return cx
-	}
-
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 
@@ -32,9 +25,27 @@ func Serve(cx context.Context, cfg *config.Config, addr string) error {
 
 	g.GET("/collections", handleListCollections(cfg))
 	g.GET("/collections/:name", handleCollect(cfg))
+	g.POST("/ingest/:name", handleIngest(cfg))
+	g.POST("/otlp/:name/v1/logs", handleOtlpLogs(cfg))
+	g.POST("/journal-remote/:name/upload", handleJournalUpload(cfg))
+
+	e.POST("/v1/logs", handleOtlpLogs(cfg))
+	e.POST("/upload", handleJournalUpload(cfg))
 
 	e.GET("*", web.Static())
 
+	return e
+}
+
+func Serve(cx context.Context, cfg *config.Config, addr string) error {
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+
+	e := newEcho(cfg)
+	e.Server.BaseContext = func(l net.Listener) context.Context {
+		return cx
+	}
+
 	wg.Add(1)
 	context.AfterFunc(cx, func() {
 		defer wg.Done()
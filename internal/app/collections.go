@@ -1,13 +1,16 @@
 package app
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/ysuzuki-bysystems/seigo/internal/config"
 	"github.com/ysuzuki-bysystems/seigo/internal/datasource"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient"
 	"github.com/ysuzuki-bysystems/seigo/internal/types"
 )
 
@@ -35,15 +38,46 @@ func handleListCollections(cfg *config.Config) echo.HandlerFunc {
 	}
 }
 
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type hostKeyErrorResponse struct {
+	Error       string `json:"error"`
+	Hostname    string `json:"hostname"`
+	KeyType     string `json:"keyType"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// EventSource does not expose the response on failure. The reason is sent as an `error` event instead.
+func writeErrorEvent(w *echo.Response, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	if _, err := fmt.Fprintf(w, "event:error\r\ndata:%s\r\n\r\n", b); err != nil {
+		return err
+	}
+	w.Flush()
+
+	return nil
+}
+
 type collectRequest struct {
 	Name  string     `param:"name"`
 	Since *time.Time `query:"since"`
+	Until *time.Time `query:"until"`
 	Tail  bool       `query:"tail"`
 }
 
 func handleCollect(cfg *config.Config) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		if c.Request().Header.Get("Last-Event-Id") != "" {
+		// `-` for records without cursor. Cannot resume.
+		lastEventId := c.Request().Header.Get("Last-Event-Id")
+		if lastEventId == "-" {
 			return c.NoContent(http.StatusNoContent)
 		}
 
@@ -63,6 +97,16 @@ func handleCollect(cfg *config.Config) echo.HandlerFunc {
 			} else {
 				opts.Since = *req.Since
 			}
+			if req.Until != nil {
+				opts.Until = *req.Until
+			}
+		}
+
+		// Resume after the last received record on reconnect.
+		opts.After = lastEventId
+		cursor := ""
+		opts.OnCursor = func(c string) {
+			cursor = c
 		}
 
 		name := req.Name
@@ -76,7 +120,18 @@ func handleCollect(cfg *config.Config) echo.HandlerFunc {
 				return c.String(404, "not found.")
 			}
 
-			return c.String(http.StatusBadRequest, "bad request.")
+			// For the user to verify the offered key.
+			var hostKeyErr *sshclient.HostKeyError
+			if errors.As(err, &hostKeyErr) {
+				return writeErrorEvent(c.Response(), &hostKeyErrorResponse{
+					Error:       hostKeyErr.Error(),
+					Hostname:    hostKeyErr.Hostname,
+					KeyType:     hostKeyErr.KeyType,
+					Fingerprint: hostKeyErr.Fingerprint,
+				})
+			}
+
+			return writeErrorEvent(c.Response(), &errorResponse{Error: "bad request."})
 		}
 
 		w := c.Response()
@@ -89,7 +144,13 @@ func handleCollect(cfg *config.Config) echo.HandlerFunc {
 				return err
 			}
 
-			if first {
+			if cursor != "" {
+				first = false
+				if _, err := fmt.Fprintf(w, "id:%s\r\n", cursor); err != nil {
+					return err
+				}
+				cursor = ""
+			} else if first {
 				first = false
 				if _, err := w.Write([]byte("id:-\r\n")); err != nil {
 					return err
@@ -0,0 +1,221 @@
+package app_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ysuzuki-bysystems/seigo/internal/app"
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource"
+	"github.com/ysuzuki-bysystems/seigo/internal/scrollbuffer"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient/sshtest"
+	"golang.org/x/crypto/ssh"
+)
+
+func newServer(t *testing.T, text string, stdin string) *httptest.Server {
+	t.Helper()
+
+	var cfg config.Config
+	if _, err := toml.Decode(text, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Path = filepath.Join(t.TempDir(), "config.toml")
+
+	buf, err := scrollbuffer.New(t.TempDir(), 1024, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = buf.Shutdown(context.Background())
+	})
+	w := buf.NewWriter()
+	if _, err := w.Write([]byte(stdin)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(app.NewHandler(&cfg))
+	server.Config.BaseContext = func(l net.Listener) context.Context {
+		return context.WithValue(context.Background(), datasource.ContextStdinBufKey, buf)
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+type event struct {
+	id    string
+	event string
+	data  string
+}
+
+func get(t *testing.T, url, lastEventId string) (*http.Response, []event) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-Id", lastEventId)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	events := []event{}
+	var ev event
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			events = append(events, ev)
+			ev = event{}
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		switch name {
+		case "id":
+			ev.id = value
+		case "event":
+			ev.event = value
+		case "data":
+			ev.data = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return resp, events
+}
+
+func TestCollectResume(t *testing.T) {
+	server := newServer(t, `[[collection]]
+name = "default"
+type = "stdin"
+`, `{"n":1}
+{"n":2}
+{"n":3}
+`)
+	url := server.URL + "/api/collections/default"
+
+	resp, events := get(t, url, "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("%d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if len(events) != 4 || events[3].event != "eof" {
+		t.Fatalf("%#v", events)
+	}
+	ids := []string{}
+	for _, ev := range events[:3] {
+		ids = append(ids, ev.id)
+	}
+	if ids[0] == "" || ids[0] == "-" || ids[0] == ids[1] || ids[1] == ids[2] {
+		t.Fatalf("%#v", ids)
+	}
+
+	// Reconnected after the first record. Neither duplicated nor lost.
+	_, resumed := get(t, url, ids[0])
+	recv := []string{}
+	for _, ev := range resumed {
+		if ev.event == "" {
+			recv = append(recv, ev.data)
+		}
+	}
+	wants := []string{`{"n":2}`, `{"n":3}`}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+	if resumed[0].id != ids[1] {
+		t.Fatalf("%s != %s", resumed[0].id, ids[1])
+	}
+
+	// Resumed after the last one.
+	_, resumed = get(t, url, ids[2])
+	if len(resumed) != 1 || resumed[0].event != "eof" {
+		t.Fatalf("%#v", resumed)
+	}
+}
+
+func TestCollectWithoutCursor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("{\"n\":1}\n{\"n\":2}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newServer(t, `[[collection]]
+name = "default"
+type = "file"
+path = "`+path+`"
+`, "")
+	url := server.URL + "/api/collections/default"
+
+	_, events := get(t, url, "")
+	ids := []string{}
+	for _, ev := range events {
+		ids = append(ids, ev.id)
+	}
+	// Only the first one. Cannot resume.
+	wants := []string{"-", "", ""}
+	if !slices.Equal(wants, ids) {
+		t.Fatalf("%#v != %#v", wants, ids)
+	}
+
+	resp, events := get(t, url, "-")
+	if resp.StatusCode != http.StatusNoContent || len(events) != 0 {
+		t.Fatalf("%d %#v", resp.StatusCode, events)
+	}
+}
+
+func TestCollectHostKeyError(t *testing.T) {
+	sshServer := sshtest.NewServer(t)
+	fingerprint := ssh.FingerprintSHA256(sshServer.HostKey)
+
+	server := newServer(t, fmt.Sprintf(`[[collection]]
+name = "default"
+type = "ssh+exec"
+command = "true"
+hostname = %q
+port = %d
+username = %q
+identity-file = %q
+user-known-hosts-file = %q
+ssh-config-file = "none"
+hostkey-fingerprints = ["SHA256:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"]
+`, sshServer.Config.Hostname, sshServer.Config.Port, sshServer.Config.Username, sshServer.Config.IdentityFile, sshServer.Config.UserKnownHostsFile), "")
+
+	resp, events := get(t, server.URL+"/api/collections/default", "")
+	if resp.StatusCode != http.StatusOK || len(events) != 1 || events[0].event != "error" {
+		t.Fatalf("%d %#v", resp.StatusCode, events)
+	}
+
+	var body struct {
+		Error       string `json:"error"`
+		Hostname    string `json:"hostname"`
+		KeyType     string `json:"keyType"`
+		Fingerprint string `json:"fingerprint"`
+	}
+	if err := json.Unmarshal([]byte(events[0].data), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Fingerprint != fingerprint || body.KeyType != sshServer.HostKey.Type() || body.Hostname == "" || body.Error == "" {
+		t.Fatalf("%#v", body)
+	}
+}
@@ -0,0 +1,11 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+)
+
+func NewHandler(cfg *config.Config) http.Handler {
+	return newEcho(cfg)
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource"
+)
+
+type ingestRequest struct {
+	Name string `param:"name"`
+}
+
+// Decode the request body by `Content-Encoding`.
+func requestBody(c echo.Context) (io.ReadCloser, error) {
+	body := c.Request().Body
+
+	switch c.Request().Header.Get(echo.HeaderContentEncoding) {
+	case "", "identity":
+		return body, nil
+	case "gzip":
+		r, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "bad request.")
+		}
+		return r, nil
+	default:
+		return nil, echo.NewHTTPError(http.StatusUnsupportedMediaType, "unsupported encoding.")
+	}
+}
+
+func handleIngest(cfg *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cx := c.Request().Context()
+
+		// Do not bind the body.
+		var req ingestRequest
+		if err := (&echo.DefaultBinder{}).BindPathParams(c, &req); err != nil {
+			return err
+		}
+
+		body, err := requestBody(c)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		result, err := datasource.Ingest(cx, cfg, req.Name, body)
+		if err != nil {
+			if errors.Is(err, datasource.ErrCollectionNotFound) {
+				return c.String(http.StatusNotFound, "not found.")
+			}
+
+			return err
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource"
+)
+
+// Compatible with `systemd-journal-remote`, for `systemd-journal-upload`.
+// Without name, records are delivered to all `journal-remote` collections.
+func handleJournalUpload(cfg *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cx := c.Request().Context()
+		name := c.Param("name")
+
+		contentType := c.Request().Header.Get(echo.HeaderContentType)
+		body, err := requestBody(c)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		if err := datasource.ReceiveJournalExport(cx, cfg, name, contentType, body); err != nil {
+			if errors.Is(err, datasource.ErrCollectionNotFound) {
+				return c.String(http.StatusNotFound, "not found.")
+			}
+			if errors.Is(err, datasource.ErrUnsupportedMediaType) {
+				return c.String(http.StatusUnsupportedMediaType, "unsupported media type.")
+			}
+
+			return c.String(http.StatusBadRequest, "bad request.")
+		}
+
+		return c.String(http.StatusAccepted, "OK.")
+	}
+}
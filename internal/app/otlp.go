@@ -0,0 +1,52 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource"
+)
+
+const contentTypeProtobuf = "application/x-protobuf"
+
+// https://opentelemetry.io/docs/specs/otlp/#otlphttp-request
+// Without name, records are delivered to all `otlp` collections.
+func handleOtlpLogs(cfg *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cx := c.Request().Context()
+		name := c.Param("name")
+
+		body, err := requestBody(c)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+
+		contentType := c.Request().Header.Get(echo.HeaderContentType)
+		if err := datasource.ReceiveOtlpLogs(cx, cfg, name, contentType, data); err != nil {
+			if errors.Is(err, datasource.ErrCollectionNotFound) {
+				return c.String(http.StatusNotFound, "not found.")
+			}
+			if errors.Is(err, datasource.ErrUnsupportedMediaType) {
+				return c.String(http.StatusUnsupportedMediaType, "unsupported media type.")
+			}
+
+			return c.String(http.StatusBadRequest, "bad request.")
+		}
+
+		// Empty ExportLogsServiceResponse
+		if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == contentTypeProtobuf {
+			return c.Blob(http.StatusOK, contentTypeProtobuf, []byte{})
+		}
+		return c.JSONBlob(http.StatusOK, []byte("{}"))
+	}
+}
@@ -15,6 +15,10 @@ import (
 type datasourceEnv struct {
 	cfg  json.RawMessage
 	path string
+	name string
+
+	// Whole config. For datasources which refer to other collections.
+	config *config.Config
 }
 
 func (d *datasourceEnv) unmarshalConfig(dst any) error {
@@ -62,8 +66,10 @@ func Collect(cx context.Context, cfg *config.Config, name string, opts *types.Co
 	ds := v.(datasource)
 
 	env := &datasourceEnv{
-		cfg:  collection.Opts,
-		path: cfg.Path,
+		cfg:    collection.Opts,
+		path:   cfg.Path,
+		name:   collection.Name,
+		config: cfg,
 	}
 
 	return ds.collect(cx, env, opts)
@@ -0,0 +1,27 @@
+//go:build !no_docker
+
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/docker"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type dockerDatasource struct{}
+
+func (d *dockerDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	var cfg docker.DockerConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return docker.DockerCollect(cx, &cfg, opts)
+}
+
+func init() {
+	registerDatasource("docker", new(dockerDatasource))
+}
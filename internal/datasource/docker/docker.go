@@ -0,0 +1,390 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/merge"
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+	"github.com/ysuzuki-bysystems/seigo/internal/record"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+var defaultHost = "unix:///var/run/docker.sock"
+
+type DockerConfig struct {
+	parser.Config
+	// `unix:///path/to/socket` or `tcp://host:port`. Defaults to `$DOCKER_HOST`.
+	Host string `json:"host"`
+	// e.g. `v1.43`. Empty means the latest version of the engine.
+	ApiVersion     string   `json:"api-version"`
+	Containers     []string `json:"containers"`
+	Labels         []string `json:"labels"`
+	ComposeProject string   `json:"compose-project"`
+}
+
+type client struct {
+	http *http.Client
+	base string
+}
+
+func newClient(cfg *DockerConfig) (*client, error) {
+	host := cfg.Host
+	if host == "" {
+		if val, ok := os.LookupEnv("DOCKER_HOST"); ok && val != "" {
+			host = val
+		} else {
+			host = defaultHost
+		}
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{}
+	var base string
+	switch u.Scheme {
+	case "unix":
+		sock := u.Path
+		transport.DialContext = func(cx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(cx, "unix", sock)
+		}
+		base = "http://docker"
+	case "tcp", "http":
+		base = fmt.Sprintf("http://%s", u.Host)
+	case "https":
+		base = fmt.Sprintf("https://%s", u.Host)
+	default:
+		return nil, fmt.Errorf("Unsupported host: %s", host)
+	}
+
+	if cfg.ApiVersion != "" {
+		base = fmt.Sprintf("%s/%s", base, strings.TrimPrefix(cfg.ApiVersion, "/"))
+	}
+
+	return &client{
+		http: &http.Client{Transport: transport},
+		base: base,
+	}, nil
+}
+
+func (c *client) get(cx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := c.base + path
+	if len(query) > 0 {
+		u = fmt.Sprintf("%s?%s", u, query.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(cx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		var msg struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&msg)
+		return nil, fmt.Errorf("GET %s: %s %s", path, resp.Status, msg.Message)
+	}
+
+	return resp, nil
+}
+
+func (c *client) getJSON(cx context.Context, path string, query url.Values, dst any) error {
+	resp, err := c.get(cx, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+type containerSummary struct {
+	Id    string   `json:"Id"`
+	Names []string `json:"Names"`
+}
+
+type containerInspect struct {
+	Id     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Tty bool `json:"Tty"`
+	} `json:"Config"`
+}
+
+func listContainers(cx context.Context, c *client, cfg *DockerConfig, all bool) ([]*containerInspect, error) {
+	filters := make(map[string][]string)
+	if len(cfg.Containers) > 0 {
+		filters["name"] = cfg.Containers
+	}
+	labels := append([]string{}, cfg.Labels...)
+	if cfg.ComposeProject != "" {
+		labels = append(labels, fmt.Sprintf("com.docker.compose.project=%s", cfg.ComposeProject))
+	}
+	if len(labels) > 0 {
+		filters["label"] = labels
+	}
+
+	query := url.Values{}
+	if all {
+		query.Set("all", "true")
+	}
+	if len(filters) > 0 {
+		b, err := json.Marshal(filters)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("filters", string(b))
+	}
+
+	var summaries []containerSummary
+	if err := c.getJSON(cx, "/containers/json", query, &summaries); err != nil {
+		return nil, err
+	}
+
+	result := make([]*containerInspect, 0, len(summaries))
+	for _, s := range summaries {
+		var inspect containerInspect
+		if err := c.getJSON(cx, fmt.Sprintf("/containers/%s/json", url.PathEscape(s.Id)), nil, &inspect); err != nil {
+			return nil, err
+		}
+		result = append(result, &inspect)
+	}
+
+	return result, nil
+}
+
+// `<RFC 3339> <line>` by `timestamps=true`.
+func cutTimestamp(line []byte) (time.Time, []byte, bool) {
+	prefix, rest, ok := bytes.Cut(line, []byte(" "))
+	if !ok {
+		prefix, rest = bytes.TrimRight(line, "\r\n"), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(prefix))
+	if err != nil {
+		return time.Time{}, line, false
+	}
+	return t, rest, true
+}
+
+// Split by lines. A line longer than the log driver's buffer arrives in multiple chunks, so keep the rest until newline.
+type lineBuffer struct {
+	buf []byte
+}
+
+func (l *lineBuffer) write(b []byte, fn func([]byte) bool) bool {
+	if len(l.buf) > 0 {
+		// Each chunk is prefixed with its timestamp.
+		_, b, _ = cutTimestamp(b)
+	}
+	l.buf = append(l.buf, b...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			return true
+		}
+
+		line := l.buf[:i]
+		l.buf = l.buf[i+1:]
+		if !fn(line) {
+			return false
+		}
+	}
+}
+
+func (l *lineBuffer) flush(fn func([]byte) bool) bool {
+	if len(l.buf) == 0 {
+		return true
+	}
+
+	line := l.buf
+	l.buf = nil
+	return fn(line)
+}
+
+// https://docs.docker.com/reference/api/engine/version/v1.47/#tag/Container/operation/ContainerAttach
+// > The stream format is a header followed by the payload ... header := [8]byte{STREAM_TYPE, 0, 0, 0, SIZE1, SIZE2, SIZE3, SIZE4}
+func demux(r io.Reader, fn func([]byte) bool) error {
+	header := make([]byte, 8)
+	bufs := map[byte]*lineBuffer{}
+	payload := make([]byte, 0, 4096)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		size := binary.BigEndian.Uint32(header[4:])
+		if cap(payload) < int(size) {
+			payload = make([]byte, size)
+		}
+		payload = payload[:size]
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return err
+		}
+
+		lb, ok := bufs[header[0]]
+		if !ok {
+			lb = new(lineBuffer)
+			bufs[header[0]] = lb
+		}
+		if !lb.write(payload, fn) {
+			return nil
+		}
+	}
+
+	for _, lb := range bufs {
+		if !lb.flush(fn) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func readRaw(r io.Reader, fn func([]byte) bool) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if !fn(line) {
+				return nil
+			}
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Lines are prefixed with their timestamps to order the containers.
+// In tail mode, lines since the time are followed. Zero means only new lines.
+func streamLogs(cx context.Context, c *client, conv *parser.Converter, container *containerInspect, opts *types.CollectOpts, since time.Time, yield func(*merge.Entry, error) bool) bool {
+	query := url.Values{}
+	query.Set("stdout", "true")
+	query.Set("stderr", "true")
+	query.Set("timestamps", "true")
+	if opts.Tail {
+		query.Set("follow", "true")
+		if since.IsZero() {
+			query.Set("tail", "0")
+		} else {
+			query.Set("since", fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()))
+		}
+	} else {
+		since := opts.Since
+		query.Set("since", fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()))
+		if until := opts.Until; !until.IsZero() {
+			query.Set("until", fmt.Sprintf("%d.%09d", until.Unix(), until.Nanosecond()))
+		}
+	}
+
+	resp, err := c.get(cx, fmt.Sprintf("/containers/%s/logs", url.PathEscape(container.Id)), query)
+	if err != nil {
+		return yield(nil, err)
+	}
+	defer resp.Body.Close()
+
+	tags := map[string]any{
+		"_container": strings.TrimPrefix(container.Name, "/"),
+	}
+
+	cont := true
+	var convErr error
+	onLine := func(line []byte) bool {
+		t, line, _ := cutTimestamp(line)
+		raw, err := conv.Convert(bytes.TrimRight(line, "\r\n"))
+		if err == nil && raw != nil {
+			raw, err = record.Merge(raw, tags)
+		}
+		if err != nil {
+			convErr = err
+			return false
+		}
+		if raw == nil {
+			// drop & skip
+			return true
+		}
+
+		cont = yield(&merge.Entry{Raw: raw, Time: t}, nil)
+		return cont
+	}
+
+	if container.Config.Tty {
+		err = readRaw(resp.Body, onLine)
+	} else {
+		err = demux(resp.Body, onLine)
+	}
+
+	if convErr != nil {
+		return yield(nil, convErr)
+	}
+	if err != nil && cx.Err() == nil {
+		return yield(nil, err)
+	}
+	return cont
+}
+
+func DockerCollect(cx context.Context, cfg *DockerConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	conv, err := parser.NewConverter(&cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Running ones are listed again in tail mode.
+	list := func(cx context.Context) ([]merge.Stream, error) {
+		containers, err := listContainers(cx, c, cfg, !opts.Tail)
+		if err != nil {
+			return nil, err
+		}
+
+		streams := make([]merge.Stream, 0, len(containers))
+		for _, container := range containers {
+			streams = append(streams, merge.Stream{
+				Key: container.Id,
+				Open: func(cx context.Context, since time.Time) iter.Seq2[*merge.Entry, error] {
+					return func(yield func(*merge.Entry, error) bool) {
+						streamLogs(cx, c, conv, container, opts, since, yield)
+					}
+				},
+			})
+		}
+		return streams, nil
+	}
+	return merge.Streams(cx, opts.Tail, list)
+}
@@ -0,0 +1,146 @@
+package docker_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/docker"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func newEngine(t *testing.T) string {
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1.43/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		var filters map[string][]string
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		want := map[string][]string{
+			"name":  {"web"},
+			"label": {"com.docker.compose.project=proj"},
+		}
+		if fmt.Sprint(filters) != fmt.Sprint(want) {
+			http.Error(w, fmt.Sprint(filters), http.StatusBadRequest)
+			return
+		}
+
+		_, _ = w.Write([]byte(`[{"Id":"aaa","Names":["/web-1"]},{"Id":"bbb","Names":["/web-2"]}]`))
+	})
+	mux.HandleFunc("GET /v1.43/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		tty := id == "bbb"
+		_, _ = fmt.Fprintf(w, `{"Id":%q,"Name":"/web-%s","Config":{"Tty":%t}}`, id, id, tty)
+	})
+	mux.HandleFunc("GET /v1.43/containers/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("since") != "1700000000.000000000" || r.URL.Query().Get("timestamps") != "true" {
+			http.Error(w, r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+
+		// Each chunk of a long line is prefixed with its timestamp.
+		switch r.PathValue("id") {
+		case "aaa":
+			_, _ = w.Write(frame(1, `2024-01-01T00:00:02.000000000Z {"id":"aaa",`))
+			_, _ = w.Write(frame(2, "2024-01-01T00:00:01.500000000Z stderr line\n"))
+			_, _ = w.Write(frame(1, `2024-01-01T00:00:02.000000000Z "time":"2024-01-01T00:00:02Z"}`+"\n"))
+		case "bbb":
+			_, _ = w.Write([]byte("2024-01-01T00:00:01.000000000Z {\"id\":\"bbb\",\"time\":\"2024-01-01T00:00:01Z\"}\n"))
+		}
+	})
+
+	server := httptest.NewUnstartedServer(mux)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return fmt.Sprintf("unix://%s", sock)
+}
+
+func collectAll(t *testing.T, cfg *docker.DockerConfig) []string {
+	t.Helper()
+
+	opts := &types.CollectOpts{
+		Since: time.Unix(1700000000, 0),
+	}
+	iter, err := docker.DockerCollect(t.Context(), cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv = append(recv, string(ent))
+	}
+	return recv
+}
+
+func TestDockerCollect(t *testing.T) {
+	host := newEngine(t)
+
+	cfg := &docker.DockerConfig{
+		Host:           host,
+		ApiVersion:     "v1.43",
+		Containers:     []string{"web"},
+		ComposeProject: "proj",
+	}
+	recv := collectAll(t, cfg)
+
+	// Ordered by timestamp.
+	wants := []string{
+		`{"id":"bbb","time":"2024-01-01T00:00:01Z","_container":"web-bbb"}`,
+		`{"id":"aaa","time":"2024-01-01T00:00:02Z","_container":"web-aaa"}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
+
+func TestDockerCollectNonJson(t *testing.T) {
+	host := newEngine(t)
+
+	cfg := &docker.DockerConfig{
+		Host:           host,
+		ApiVersion:     "v1.43",
+		Containers:     []string{"web"},
+		ComposeProject: "proj",
+	}
+	cfg.NonJson = "wrap"
+	recv := collectAll(t, cfg)
+
+	// By the timestamps of the lines.
+	wants := []string{
+		`{"id":"bbb","time":"2024-01-01T00:00:01Z","_container":"web-bbb"}`,
+		`{"message":"stderr line","_raw":true,"_container":"web-aaa"}`,
+		`{"id":"aaa","time":"2024-01-01T00:00:02Z","_container":"web-aaa"}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
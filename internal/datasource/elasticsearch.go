@@ -0,0 +1,27 @@
+//go:build !no_elasticsearch
+
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/elasticsearch"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type elasticsearchDatasource struct{}
+
+func (d *elasticsearchDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	var cfg elasticsearch.ElasticsearchConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return elasticsearch.ElasticsearchCollect(cx, env.path, &cfg, opts)
+}
+
+func init() {
+	registerDatasource("elasticsearch", new(elasticsearchDatasource))
+}
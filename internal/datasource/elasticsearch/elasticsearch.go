@@ -0,0 +1,277 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+var defaultPageSize = 1000
+
+var defaultPollInterval = 2 * time.Second
+
+func resolvePath(cfgPath, target string) string {
+	if filepath.IsAbs(target) {
+		return target
+	}
+
+	dir := filepath.Dir(cfgPath)
+	return filepath.Join(dir, target)
+}
+
+type ElasticsearchConfig struct {
+	// e.g. `http://localhost:9200`
+	Url string `json:"url"`
+	// Index pattern. e.g. `logs-*`
+	Index string `json:"index"`
+	// Query DSL. e.g. `{ term = { "service.name" = "api" } }`
+	Query json.RawMessage `json:"query"`
+	// Query string syntax. e.g. `service.name:api AND level:error`
+	QueryString    string `json:"query-string"`
+	TimestampField string `json:"timestamp-field"`
+	// Unique field to sort documents with the same timestamp.
+	Tiebreaker string `json:"tiebreaker"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	ApiKey     string `json:"api-key"`
+	CaFile     string `json:"ca-file"`
+	PageSize   int    `json:"page-size"`
+	// Polling interval in follow mode. e.g. `2s`
+	PollInterval string `json:"poll-interval"`
+}
+
+type client struct {
+	cfg          *ElasticsearchConfig
+	http         *http.Client
+	url          string
+	pageSize     int
+	pollInterval time.Duration
+}
+
+func newClient(cfgPath string, cfg *ElasticsearchConfig) (*client, error) {
+	if cfg.Url == "" {
+		return nil, errors.New("empty url")
+	}
+	if cfg.Index == "" {
+		return nil, errors.New("empty index")
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+	}
+	if cfg.CaFile != "" {
+		ca, err := os.ReadFile(resolvePath(cfgPath, cfg.CaFile))
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("invalid ca-file")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	pageSize := cfg.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	pollInterval := defaultPollInterval
+	if cfg.PollInterval != "" {
+		d, err := time.ParseDuration(cfg.PollInterval)
+		if err != nil {
+			return nil, err
+		}
+		pollInterval = d
+	}
+
+	return &client{
+		cfg:          cfg,
+		http:         &http.Client{Transport: transport},
+		url:          fmt.Sprintf("%s/%s/_search", strings.TrimSuffix(cfg.Url, "/"), url.PathEscape(cfg.Index)),
+		pageSize:     pageSize,
+		pollInterval: pollInterval,
+	}, nil
+}
+
+func (c *client) timestampField() string {
+	if c.cfg.TimestampField == "" {
+		return "@timestamp"
+	}
+	return c.cfg.TimestampField
+}
+
+type searchResponse struct {
+	Hits struct {
+		Hits []struct {
+			Source json.RawMessage   `json:"_source"`
+			Sort   []json.RawMessage `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// until is inclusive. Zero for no limit.
+func (c *client) searchBody(since, until time.Time, after []json.RawMessage) ([]byte, error) {
+	field := c.timestampField()
+
+	timeRange := map[string]any{
+		"gte":    since.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		"format": "strict_date_optional_time",
+	}
+	if !until.IsZero() {
+		timeRange["lte"] = until.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	}
+	filters := []any{
+		map[string]any{
+			"range": map[string]any{
+				field: timeRange,
+			},
+		},
+	}
+	if len(c.cfg.Query) > 0 {
+		filters = append(filters, c.cfg.Query)
+	}
+	if c.cfg.QueryString != "" {
+		filters = append(filters, map[string]any{
+			"query_string": map[string]any{
+				"query": c.cfg.QueryString,
+			},
+		})
+	}
+
+	sort := []any{
+		map[string]any{field: map[string]any{"order": "asc"}},
+	}
+	if c.cfg.Tiebreaker != "" {
+		sort = append(sort, map[string]any{c.cfg.Tiebreaker: map[string]any{"order": "asc"}})
+	}
+
+	body := map[string]any{
+		"size": c.pageSize,
+		"query": map[string]any{
+			"bool": map[string]any{
+				"filter": filters,
+			},
+		},
+		"sort": sort,
+	}
+	if after != nil {
+		body["search_after"] = after
+	}
+
+	return json.Marshal(body)
+}
+
+func (c *client) search(cx context.Context, since, until time.Time, after []json.RawMessage) (*searchResponse, error) {
+	body, err := c.searchBody(since, until, after)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(cx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch {
+	case c.cfg.ApiKey != "":
+		req.Header.Set("Authorization", fmt.Sprintf("ApiKey %s", c.cfg.ApiKey))
+	case c.cfg.Username != "":
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("_search: %s %s", resp.Status, msg)
+	}
+
+	var result searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Emit documents after `after` until no more pages. Returns the sort values of the last document.
+func (c *client) drain(cx context.Context, since, until time.Time, after []json.RawMessage, yield func(json.RawMessage, error) bool) ([]json.RawMessage, bool) {
+	for {
+		result, err := c.search(cx, since, until, after)
+		if err != nil {
+			if cx.Err() != nil {
+				return after, false
+			}
+			yield(nil, err)
+			return after, false
+		}
+
+		hits := result.Hits.Hits
+		for _, hit := range hits {
+			after = hit.Sort
+			if !yield(hit.Source, nil) {
+				return after, false
+			}
+		}
+
+		if len(hits) < c.pageSize {
+			return after, true
+		}
+	}
+}
+
+func ElasticsearchCollect(cx context.Context, cfgPath string, cfg *ElasticsearchConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	c, err := newClient(cfgPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Tail {
+		return func(yield func(json.RawMessage, error) bool) {
+			c.drain(cx, opts.Since, opts.Until, nil, yield)
+		}, nil
+	}
+
+	// Follow by polling newer documents.
+	// Documents indexed later than the poll with older timestamps are not emitted.
+	return func(yield func(json.RawMessage, error) bool) {
+		since := time.Now()
+		var after []json.RawMessage
+
+		ticker := time.NewTicker(c.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-cx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			var ok bool
+			after, ok = c.drain(cx, since, time.Time{}, after, yield)
+			if !ok {
+				return
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,240 @@
+package elasticsearch_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/elasticsearch"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type document struct {
+	ts time.Time
+	id string
+}
+
+type searchRequest struct {
+	Size  int `json:"size"`
+	Query struct {
+		Bool struct {
+			Filter []struct {
+				Range map[string]struct {
+					Gte string `json:"gte"`
+					Lte string `json:"lte"`
+				} `json:"range"`
+				Term map[string]string `json:"term"`
+			} `json:"filter"`
+		} `json:"bool"`
+	} `json:"query"`
+	SearchAfter []any `json:"search_after"`
+}
+
+type index struct {
+	mu   sync.Mutex
+	docs []document
+}
+
+func (i *index) add(doc document) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.docs = append(i.docs, doc)
+}
+
+func newElasticsearch(t *testing.T, idx *index) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /logs-app/_search", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "ApiKey secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req searchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		filters := req.Query.Bool.Filter
+		if len(filters) != 2 || filters[1].Term["service"] != "api" {
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+			return
+		}
+		since, err := time.Parse(time.RFC3339, filters[0].Range["@timestamp"].Gte)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var until time.Time
+		if lte := filters[0].Range["@timestamp"].Lte; lte != "" {
+			until, err = time.Parse(time.RFC3339, lte)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		var afterTs int64
+		var afterId string
+		if len(req.SearchAfter) == 2 {
+			afterTs = int64(req.SearchAfter[0].(float64))
+			afterId = req.SearchAfter[1].(string)
+		}
+
+		idx.mu.Lock()
+		defer idx.mu.Unlock()
+
+		hits := make([]any, 0)
+		for _, doc := range idx.docs {
+			if doc.ts.Before(since) || !until.IsZero() && doc.ts.After(until) || len(hits) >= req.Size {
+				continue
+			}
+			ms := doc.ts.UnixMilli()
+			if req.SearchAfter != nil && (ms < afterTs || ms == afterTs && doc.id <= afterId) {
+				continue
+			}
+			hits = append(hits, map[string]any{
+				"_source": map[string]any{"@timestamp": doc.ts.Format(time.RFC3339Nano), "id": doc.id},
+				"sort":    []any{ms, doc.id},
+			})
+		}
+
+		body := map[string]any{
+			"hits": map[string]any{
+				"hits": hits,
+			},
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestElasticsearchCollect(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	idx := &index{
+		docs: []document{
+			{ts: base, id: "a"},
+			{ts: base.Add(time.Second), id: "b"},
+			{ts: base.Add(time.Second), id: "c"},
+			{ts: base.Add(2 * time.Second), id: "d"},
+			{ts: base.Add(3 * time.Second), id: "e"},
+		},
+	}
+	server := newElasticsearch(t, idx)
+
+	cfg := &elasticsearch.ElasticsearchConfig{
+		Url:          server.URL,
+		Index:        "logs-app",
+		Query:        json.RawMessage(`{"term":{"service":"api"}}`),
+		Tiebreaker:   "id",
+		ApiKey:       "secret",
+		PageSize:     2,
+		PollInterval: "10ms",
+	}
+
+	t.Run("history", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Since: base.Add(time.Second),
+		}
+		iter, err := elasticsearch.ElasticsearchCollect(t.Context(), "", cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc struct {
+				Id string `json:"id"`
+			}
+			if err := json.Unmarshal(ent, &doc); err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, doc.Id)
+		}
+
+		wants := []string{"b", "c", "d", "e"}
+		if !slices.Equal(wants, recv) {
+			t.Fatalf("%#v != %#v", wants, recv)
+		}
+	})
+
+	t.Run("until", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Since: base,
+			Until: base.Add(time.Second),
+		}
+		iter, err := elasticsearch.ElasticsearchCollect(t.Context(), "", cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc struct {
+				Id string `json:"id"`
+			}
+			if err := json.Unmarshal(ent, &doc); err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, doc.Id)
+		}
+
+		wants := []string{"a", "b", "c"}
+		if !slices.Equal(wants, recv) {
+			t.Fatalf("%#v != %#v", wants, recv)
+		}
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Tail: true,
+		}
+		iter, err := elasticsearch.ElasticsearchCollect(t.Context(), "", cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			for i := range 3 {
+				idx.add(document{ts: time.Now().Add(time.Second), id: fmt.Sprint("new", i)})
+			}
+		}()
+
+		recv := []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc struct {
+				Id string `json:"id"`
+			}
+			if err := json.Unmarshal(ent, &doc); err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, doc.Id)
+			if len(recv) == 3 {
+				break
+			}
+		}
+
+		wants := []string{"new0", "new1", "new2"}
+		if !slices.Equal(wants, recv) {
+			t.Fatalf("%#v != %#v", wants, recv)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+//go:build !no_exec
+
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/exec"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type execDatasource struct{}
+
+func (d *execDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	var cfg exec.ExecConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return exec.ExecCollect(cx, env.path, &cfg, opts)
+}
+
+func init() {
+	registerDatasource("exec", new(execDatasource))
+}
+
+type sshExecDatasource struct{}
+
+func (d *sshExecDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	var cfg exec.SshExecConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return exec.SshExecCollect(cx, env.path, &cfg, opts)
+}
+
+func init() {
+	registerDatasource("ssh+exec", new(sshExecDatasource))
+}
@@ -0,0 +1,148 @@
+package exec
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+	"github.com/ysuzuki-bysystems/seigo/internal/proc"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type ExecConfig struct {
+	parser.Config
+	Command string `json:"command"`
+	// text/template for each argument.
+	// `{{since}}` ... RFC 3339 (`{{since "unix"}}` or `{{since "<Go layout>"}}` for other formats)
+	// `{{until}}` ... Same as since. Empty without the end
+	// `{{follow}}` ... true in tail mode
+	// Arguments rendered to empty string are omitted.
+	Args []string `json:"args"`
+	// Additional environment variables.
+	Env map[string]string `json:"env"`
+}
+
+func formatTime(t time.Time, layout []string) (string, error) {
+	switch len(layout) {
+	case 0:
+		return t.Format(time.RFC3339), nil
+	case 1:
+		if layout[0] == "unix" {
+			return strconv.FormatInt(t.Unix(), 10), nil
+		}
+		return t.Format(layout[0]), nil
+	}
+	return "", errors.New("too many arguments")
+}
+
+func funcs(opts *types.CollectOpts) template.FuncMap {
+	return template.FuncMap{
+		"since": func(layout ...string) (string, error) {
+			if opts.Tail {
+				return "", nil
+			}
+			return formatTime(opts.Since, layout)
+		},
+		"until": func(layout ...string) (string, error) {
+			if opts.Tail || opts.Until.IsZero() {
+				return "", nil
+			}
+			return formatTime(opts.Until, layout)
+		},
+		"follow": func() bool {
+			return opts.Tail
+		},
+	}
+}
+
+func renderArgs(cfg *ExecConfig, opts *types.CollectOpts) ([]string, error) {
+	fm := funcs(opts)
+
+	args := make([]string, 0, len(cfg.Args))
+	for i, arg := range cfg.Args {
+		tmpl, err := template.New(fmt.Sprintf("args[%d]", i)).Funcs(fm).Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		var b strings.Builder
+		if err := tmpl.Execute(&b, nil); err != nil {
+			return nil, err
+		}
+		if b.Len() == 0 {
+			continue
+		}
+		args = append(args, b.String())
+	}
+
+	return args, nil
+}
+
+func iterRecords(conv *parser.Converter, stdout io.Reader, onDone func()) iter.Seq2[json.RawMessage, error] {
+	return func(yield func(json.RawMessage, error) bool) {
+		defer onDone()
+
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+
+			raw, err := conv.Convert(line)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if raw == nil {
+				// drop & skip
+				continue
+			}
+
+			if !yield(raw, nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+func ExecCollect(cx context.Context, cfgPath string, cfg *ExecConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	if cfg.Command == "" {
+		return nil, errors.New("empty command")
+	}
+
+	conv, err := parser.NewConverter(&cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := renderArgs(cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := proc.Command(cx, proc.ResolveBin(cfgPath, cfg.Command), args...)
+	if len(cfg.Env) > 0 {
+		cmd.Env = os.Environ()
+		for key, val := range cfg.Env {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, val))
+		}
+	}
+
+	stdout, onDone, err := proc.Start(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return iterRecords(conv, stdout, onDone), nil
+}
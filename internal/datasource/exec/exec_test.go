@@ -0,0 +1,129 @@
+package exec_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/exec"
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+func TestExecCollect(t *testing.T) {
+	dummyCfgPath := "./testdata/config.toml" // not exists
+	cfg := &exec.ExecConfig{
+		Command: "./exec.sh",
+		Args: []string{
+			"logs",
+			"{{if follow}}--follow{{end}}",
+			"{{with since}}--since={{.}}{{end}}",
+			`--until={{since "unix"}}`,
+			`{{with until "unix"}}--before={{.}}{{end}}`,
+		},
+		Env: map[string]string{
+			"EXEC_TEST": "ok",
+		},
+	}
+
+	tests := []struct {
+		name  string
+		opts  *types.CollectOpts
+		wants []string
+	}{
+		{
+			name: "history",
+			opts: &types.CollectOpts{
+				Since: time.Unix(0, 0).UTC(),
+			},
+			wants: []string{
+				`{"arg":"logs"}`,
+				`{"arg":"--since=1970-01-01T00:00:00Z"}`,
+				`{"arg":"--until=0"}`,
+				`{"env":"ok"}`,
+			},
+		},
+		{
+			name: "until",
+			opts: &types.CollectOpts{
+				Since: time.Unix(0, 0).UTC(),
+				Until: time.Unix(60, 0).UTC(),
+			},
+			wants: []string{
+				`{"arg":"logs"}`,
+				`{"arg":"--since=1970-01-01T00:00:00Z"}`,
+				`{"arg":"--until=0"}`,
+				`{"arg":"--before=60"}`,
+				`{"env":"ok"}`,
+			},
+		},
+		{
+			name: "tail",
+			opts: &types.CollectOpts{
+				Tail: true,
+			},
+			wants: []string{
+				`{"arg":"logs"}`,
+				`{"arg":"--follow"}`,
+				`{"arg":"--until="}`,
+				`{"env":"ok"}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iter, err := exec.ExecCollect(t.Context(), dummyCfgPath, cfg, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recv := []string{}
+			for ent, err := range iter {
+				if err != nil {
+					t.Fatal(err)
+				}
+				recv = append(recv, string(ent))
+			}
+
+			if !slices.Equal(tt.wants, recv) {
+				t.Fatalf("%#v != %#v", tt.wants, recv)
+			}
+		})
+	}
+}
+
+func TestExecCollectParser(t *testing.T) {
+	cfg := &exec.ExecConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo 'level=info msg=hello'; echo 'not logfmt'; echo 'level=warn'"},
+	}
+	cfg.Parser = "logfmt"
+	cfg.NonJson = "error"
+
+	iter, err := exec.ExecCollect(t.Context(), "./testdata/config.toml", cfg, &types.CollectOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	var lastErr error
+	for ent, err := range iter {
+		if err != nil {
+			lastErr = err
+			break
+		}
+		recv = append(recv, string(ent))
+	}
+
+	wants := []string{
+		`{"level":"info","msg":"hello"}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+	if !errors.Is(lastErr, parser.ErrNonJson) {
+		t.Fatalf("unexpected: %v", lastErr)
+	}
+}
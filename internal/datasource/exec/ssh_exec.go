@@ -0,0 +1,61 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type SshExecConfig struct {
+	ExecConfig
+	sshclient.Config
+}
+
+func remoteCommand(cfg *ExecConfig, args []string) string {
+	words := make([]string, 0)
+	if len(cfg.Env) > 0 {
+		words = append(words, "env")
+		for _, key := range slices.Sorted(maps.Keys(cfg.Env)) {
+			words = append(words, sshclient.Quote(fmt.Sprintf("%s=%s", key, cfg.Env[key])))
+		}
+	}
+
+	words = append(words, sshclient.Quote(cfg.Command))
+	for _, arg := range args {
+		words = append(words, sshclient.Quote(arg))
+	}
+
+	return strings.Join(words, " ")
+}
+
+func SshExecCollect(cx context.Context, cfgPath string, cfg *SshExecConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	if cfg.Command == "" {
+		return nil, errors.New("empty command")
+	}
+
+	conv, err := parser.NewConverter(&cfg.ExecConfig.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := renderArgs(&cfg.ExecConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, onDone, err := sshclient.Start(cx, cfgPath, &cfg.Config, remoteCommand(&cfg.ExecConfig, args))
+	if err != nil {
+		return nil, err
+	}
+
+	return iterRecords(conv, stdout, onDone), nil
+}
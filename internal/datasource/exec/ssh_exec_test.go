@@ -0,0 +1,54 @@
+package exec_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/exec"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient/sshtest"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+func TestSshExecCollect(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	server := sshtest.NewServer(t)
+
+	cfg := &exec.SshExecConfig{
+		ExecConfig: exec.ExecConfig{
+			Command: "printf",
+			Args: []string{
+				`{"since":"%s","quote":"%s"}\n`,
+				"{{since}}",
+				"it's $HOME",
+			},
+			Env: map[string]string{
+				"LC_ALL": "C",
+			},
+		},
+		Config: server.Config,
+	}
+	opts := &types.CollectOpts{
+		Since: time.Unix(0, 0).UTC(),
+	}
+
+	iter, err := exec.SshExecCollect(t.Context(), ".", cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+		recv = append(recv, string(ent))
+	}
+
+	wants := []string{
+		`{"since":"1970-01-01T00:00:00Z","quote":"it's $HOME"}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
@@ -0,0 +1,9 @@
+#!/bin/bash
+
+while [[ "$#" -ne 0 ]]; do
+  jq -nc '{"arg":$m}' --arg m "$1"
+  shift
+done
+
+jq -nc '{"env":$m}' --arg m "$EXEC_TEST"
+echo 'not json'
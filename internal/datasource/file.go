@@ -0,0 +1,42 @@
+//go:build !no_file
+
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/file"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type fileDatasource struct{}
+
+func (d *fileDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	var cfg file.FileConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return file.FileCollect(cx, env.path, &cfg, opts)
+}
+
+func init() {
+	registerDatasource("file", new(fileDatasource))
+}
+
+type sshFileDatasource struct{}
+
+func (d *sshFileDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	var cfg file.SshFileConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return file.SshFileCollect(cx, env.path, &cfg, opts)
+}
+
+func init() {
+	registerDatasource("ssh+file", new(sshFileDatasource))
+}
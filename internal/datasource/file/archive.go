@@ -0,0 +1,288 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Compressed file extensions, and tarball shorthands.
+var compressionExts = map[string]string{
+	".gz":   ".gz",
+	".bz2":  ".bz2",
+	".zst":  ".zst",
+	".xz":   ".xz",
+	".tgz":  ".gz",
+	".tbz2": ".bz2",
+	".tzst": ".zst",
+	".txz":  ".xz",
+}
+
+type archiveKind int
+
+const (
+	archiveNone archiveKind = iota
+	archiveTar
+	archiveZip
+)
+
+// Compression extension (if any) and archive kind of the file name.
+func fileKind(name string) (string, archiveKind) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == ".zip" {
+		return "", archiveZip
+	}
+
+	compression, ok := compressionExts[ext]
+	if !ok {
+		if ext == ".tar" {
+			return "", archiveTar
+		}
+		return "", archiveNone
+	}
+
+	if ext != compression || strings.EqualFold(filepath.Ext(strings.TrimSuffix(name, filepath.Ext(name))), ".tar") {
+		return compression, archiveTar
+	}
+	return compression, archiveNone
+}
+
+// Not appendable. Excluded from tail.
+func isArchived(name string) bool {
+	compression, kind := fileKind(name)
+	return compression != "" || kind != archiveNone
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r *readCloser) Close() error {
+	return r.close()
+}
+
+func decompress(compression string, r io.Reader) (io.ReadCloser, error) {
+	switch compression {
+	case ".gz":
+		return gzip.NewReader(r)
+
+	case ".bz2":
+		return io.NopCloser(bzip2.NewReader(r)), nil
+
+	case ".zst":
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return &readCloser{Reader: dec, close: func() error {
+			dec.Close()
+			return nil
+		}}, nil
+
+	case ".xz":
+		dec, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(dec), nil
+	}
+
+	return io.NopCloser(r), nil
+}
+
+// Call fn with the content of the file, or each member of the archive.
+// Members which are compressed by themselves are also decompressed.
+// Stops when fn returns false.
+func readContents(path string, fn func(io.Reader) bool) error {
+	compression, kind := fileKind(path)
+
+	if kind == archiveZip {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+
+		for _, member := range zr.File {
+			if !member.Mode().IsRegular() {
+				continue
+			}
+
+			ok, err := func() (bool, error) {
+				mr, err := member.Open()
+				if err != nil {
+					return false, err
+				}
+				defer mr.Close()
+
+				return readMember(member.Name, mr, fn)
+			}()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	r, err := decompress(compression, fp)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if kind != archiveTar {
+		fn(r)
+		return nil
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		ok, err := readMember(header.Name, tr, fn)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+}
+
+func readMember(name string, r io.Reader, fn func(io.Reader) bool) (bool, error) {
+	compression, _ := fileKind(name)
+	dr, err := decompress(compression, r)
+	if err != nil {
+		return false, err
+	}
+	defer dr.Close()
+
+	return fn(dr), nil
+}
+
+// Rotation generation. e.g. `app.log.3.gz` -> (`app.log`, 3), `app.log` -> (`app.log`, 0)
+func rotationKey(path string) (string, int) {
+	name := path
+	for {
+		ext := strings.ToLower(filepath.Ext(name))
+		if _, ok := compressionExts[ext]; !ok && ext != ".tar" && ext != ".zip" {
+			break
+		}
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	ext := filepath.Ext(name)
+	if n, err := strconv.Atoi(strings.TrimPrefix(ext, ".")); err == nil && n >= 0 && len(ext) > 1 {
+		return strings.TrimSuffix(name, ext), n
+	}
+	return name, 0
+}
+
+// Oldest first. Ordered by modification time, but rotated generations of the same file (`app.log.2.gz`, `app.log.1`, `app.log`) are kept in the generation order.
+func sortByRotation(paths []string) []string {
+	type item struct {
+		path    string
+		modTime time.Time
+	}
+
+	items := make([]*item, 0, len(paths))
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		items = append(items, &item{path: p, modTime: fi.ModTime()})
+	}
+	slices.SortStableFunc(items, func(a, b *item) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	sorted := make([]string, 0, len(items))
+	ages := make([]int, 0, len(items))
+	for i, it := range items {
+		age := i
+		if i > 0 && it.modTime.Equal(items[i-1].modTime) {
+			age = ages[i-1]
+		}
+		sorted = append(sorted, it.path)
+		ages = append(ages, age)
+	}
+	return sortRotated(sorted, ages)
+}
+
+// Same as sortByRotation, by the ranks of the modification times instead. Older is smaller.
+func sortRotated(paths []string, ages []int) []string {
+	type item struct {
+		path       string
+		age        int
+		generation int
+	}
+
+	groups := make(map[string][]*item)
+	items := make([]*item, 0, len(paths))
+	for i, p := range paths {
+		base, generation := rotationKey(p)
+		it := &item{path: p, age: ages[i], generation: generation}
+		groups[base] = append(groups[base], it)
+		items = append(items, it)
+	}
+
+	// An older generation is not later than newer ones.
+	for _, group := range groups {
+		slices.SortFunc(group, func(a, b *item) int {
+			return a.generation - b.generation
+		})
+		for i := 1; i < len(group); i++ {
+			if group[i].age > group[i-1].age {
+				group[i].age = group[i-1].age
+			}
+		}
+	}
+
+	slices.SortStableFunc(items, func(a, b *item) int {
+		if c := a.age - b.age; c != 0 {
+			return c
+		}
+		if c := b.generation - a.generation; c != 0 {
+			return c
+		}
+		return strings.Compare(a.path, b.path)
+	})
+
+	result := make([]string, 0, len(items))
+	for _, it := range items {
+		result = append(result, it.path)
+	}
+	return result
+}
@@ -0,0 +1,496 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+	"github.com/ysuzuki-bysystems/seigo/internal/record"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+// Fallback for missed fsnotify events (e.g. network filesystems).
+var pollInterval = time.Second
+
+// How long a rotated-away file is still read after it was renamed or removed.
+var rotateGracePeriod = 5 * time.Second
+
+func resolvePath(cfgPath, target string) string {
+	if strings.HasPrefix(target, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			target = filepath.Join(home, target[2:])
+		}
+	}
+
+	if filepath.IsAbs(target) {
+		return target
+	}
+
+	dir := filepath.Dir(cfgPath)
+	return filepath.Join(dir, target)
+}
+
+type FileConfig struct {
+	parser.Config
+	// Glob pattern. e.g. `/var/log/app/*.jsonl`
+	Path           string `json:"path"`
+	TimestampField string `json:"timestamp-field"`
+}
+
+func (c *FileConfig) timestampFields() []string {
+	if c.TimestampField == "" {
+		return nil
+	}
+
+	return []string{c.TimestampField}
+}
+
+// Returns nil if the line is dropped.
+func convertLine(conv *parser.Converter, line []byte) (json.RawMessage, error) {
+	return conv.Convert(bytes.TrimRight(line, "\r\n"))
+}
+
+// Emit records since `since`, and stop at the first record after `until` (zero for no limit).
+// Records without timestamp follow the preceding record.
+// Returns false when iteration should stop.
+func readSince(cx context.Context, conv *parser.Converter, r *bufio.Reader, fields []string, since, until time.Time, keep *bool, yield func(json.RawMessage, error) bool) bool {
+	for {
+		if cx.Err() != nil {
+			return false
+		}
+
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			raw, err := convertLine(conv, line)
+			if err != nil {
+				yield(nil, err)
+				return false
+			}
+
+			if raw != nil {
+				if t, ok := record.Timestamp(raw, fields...); ok {
+					if !until.IsZero() && t.After(until) {
+						return false
+					}
+					*keep = !t.Before(since)
+				}
+
+				if *keep && !yield(raw, nil) {
+					return false
+				}
+			}
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return true
+			}
+
+			yield(nil, err)
+			return false
+		}
+	}
+}
+
+func iterHistory(cx context.Context, conv *parser.Converter, cfg *FileConfig, paths []string, since, until time.Time) iter.Seq2[json.RawMessage, error] {
+	return func(yield func(json.RawMessage, error) bool) {
+		fields := cfg.timestampFields()
+
+		for _, p := range paths {
+			keep := true
+			ok := true
+			err := readContents(p, func(r io.Reader) bool {
+				ok = readSince(cx, conv, bufio.NewReader(r), fields, since, until, &keep, yield)
+				return ok
+			})
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					// rotated away
+					continue
+				}
+
+				yield(nil, fmt.Errorf("%s: %w", p, err))
+				return
+			}
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+type tailFile struct {
+	fp      *os.File
+	info    os.FileInfo
+	offset  int64
+	pending []byte
+
+	// Set when the file is renamed or removed.
+	detachedAt time.Time
+}
+
+func openTailFile(path string, fromEnd bool) (*tailFile, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := fp.Stat()
+	if err != nil {
+		_ = fp.Close()
+		return nil, err
+	}
+
+	var offset int64
+	if fromEnd {
+		offset, err = fp.Seek(0, io.SeekEnd)
+		if err != nil {
+			_ = fp.Close()
+			return nil, err
+		}
+	}
+
+	return &tailFile{
+		fp:     fp,
+		info:   info,
+		offset: offset,
+	}, nil
+}
+
+// Read all complete lines available now.
+func (f *tailFile) readLines() ([][]byte, error) {
+	info, err := f.fp.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() < f.offset {
+		// truncated
+		if _, err := f.fp.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		f.offset = 0
+		f.pending = nil
+	}
+
+	data, err := io.ReadAll(f.fp)
+	if err != nil {
+		return nil, err
+	}
+	f.offset += int64(len(data))
+
+	buf := append(f.pending, data...)
+	lines := make([][]byte, 0)
+	for {
+		i := slices.Index(buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		lines = append(lines, buf[:i])
+		buf = buf[i+1:]
+	}
+	f.pending = slices.Clone(buf)
+
+	return lines, nil
+}
+
+type tailer struct {
+	pattern  string
+	files    map[string]*tailFile
+	detached []*tailFile
+}
+
+func (t *tailer) close() {
+	for _, f := range t.files {
+		_ = f.fp.Close()
+	}
+	for _, f := range t.detached {
+		_ = f.fp.Close()
+	}
+}
+
+func (t *tailer) detach(path string) {
+	f, ok := t.files[path]
+	if !ok {
+		return
+	}
+
+	delete(t.files, path)
+	f.detachedAt = time.Now()
+	t.detached = append(t.detached, f)
+}
+
+// Start following path if it matches the pattern and is not followed yet.
+func (t *tailer) track(path string, fromEnd bool) error {
+	if _, ok := t.files[path]; ok {
+		return nil
+	}
+
+	if ok, err := filepath.Match(t.pattern, path); err != nil || !ok {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if !info.Mode().IsRegular() || isArchived(path) {
+		return nil
+	}
+
+	// Renamed from a followed file. Continue from the current position.
+	for i, f := range t.detached {
+		if os.SameFile(f.info, info) {
+			t.detached = slices.Delete(t.detached, i, i+1)
+			f.detachedAt = time.Time{}
+			t.files[path] = f
+			return nil
+		}
+	}
+	for p, f := range t.files {
+		if os.SameFile(f.info, info) {
+			delete(t.files, p)
+			t.files[path] = f
+			return nil
+		}
+	}
+
+	f, err := openTailFile(path, fromEnd)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	t.files[path] = f
+	return nil
+}
+
+// Replaced by another file with the same name. (e.g. rotation by rename & create)
+func (t *tailer) replaced(path string) bool {
+	f, ok := t.files[path]
+	if !ok {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return true
+	}
+
+	return !os.SameFile(f.info, info)
+}
+
+func (t *tailer) scan() error {
+	paths, err := filepath.Glob(t.pattern)
+	if err != nil {
+		return err
+	}
+
+	for path := range t.files {
+		if t.replaced(path) {
+			t.detach(path)
+		}
+	}
+
+	for _, path := range paths {
+		if err := t.track(path, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func watchDirs(pattern string) ([]string, error) {
+	return filepath.Glob(filepath.Dir(pattern))
+}
+
+func iterTail(cx context.Context, conv *parser.Converter, pattern string) (iter.Seq2[json.RawMessage, error], error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := watchDirs(pattern)
+	if err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return nil, err
+		}
+	}
+
+	t := &tailer{
+		pattern: pattern,
+		files:   make(map[string]*tailFile),
+	}
+
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	for _, path := range paths {
+		// Existing contents are not emitted.
+		if err := t.track(path, true); err != nil {
+			t.close()
+			_ = watcher.Close()
+			return nil, err
+		}
+	}
+
+	return func(yield func(json.RawMessage, error) bool) {
+		defer watcher.Close()
+		defer t.close()
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		emit := func(f *tailFile) bool {
+			lines, err := f.readLines()
+			if err != nil {
+				yield(nil, err)
+				return false
+			}
+
+			for _, line := range lines {
+				raw, err := convertLine(conv, line)
+				if err != nil {
+					yield(nil, err)
+					return false
+				}
+				if raw == nil {
+					// drop & skip
+					continue
+				}
+
+				if !yield(raw, nil) {
+					return false
+				}
+			}
+
+			return true
+		}
+
+		emitAll := func() bool {
+			// Rotated files first. They are older.
+			for _, f := range t.detached {
+				if !emit(f) {
+					return false
+				}
+			}
+			for _, f := range t.files {
+				if !emit(f) {
+					return false
+				}
+			}
+			return true
+		}
+
+		for {
+			select {
+			case <-cx.Done():
+				return
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				yield(nil, err)
+				return
+
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				switch {
+				case ev.Has(fsnotify.Rename), ev.Has(fsnotify.Remove):
+					t.detach(ev.Name)
+
+				case ev.Has(fsnotify.Create), ev.Has(fsnotify.Write):
+					if t.replaced(ev.Name) {
+						t.detach(ev.Name)
+					}
+
+					if err := t.track(ev.Name, false); err != nil {
+						yield(nil, err)
+						return
+					}
+				}
+
+				if !emitAll() {
+					return
+				}
+
+			case <-ticker.C:
+				if err := t.scan(); err != nil {
+					yield(nil, err)
+					return
+				}
+
+				if !emitAll() {
+					return
+				}
+
+				now := time.Now()
+				t.detached = slices.DeleteFunc(t.detached, func(f *tailFile) bool {
+					if now.Sub(f.detachedAt) < rotateGracePeriod {
+						return false
+					}
+
+					_ = f.fp.Close()
+					return true
+				})
+			}
+		}
+	}, nil
+}
+
+func FileCollect(cx context.Context, cfgPath string, cfg *FileConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	if cfg.Path == "" {
+		return nil, errors.New("empty path")
+	}
+
+	pattern := resolvePath(cfgPath, cfg.Path)
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	conv, err := parser.NewConverter(&cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Tail {
+		return iterTail(cx, conv, pattern)
+	}
+
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return iterHistory(cx, conv, cfg, sortByRotation(paths), opts.Since, opts.Until), nil
+}
@@ -0,0 +1,370 @@
+package file_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/file"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+func TestFileCollect(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	old := filepath.Join(tmpdir, "app.1.jsonl")
+	oldData := `{"time":"2024-01-01T00:00:00Z","n":1}
+{"time":"2024-01-01T00:00:01Z","n":2}
+`
+	if err := os.WriteFile(old, []byte(oldData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(old, time.Time{}, time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	cur := filepath.Join(tmpdir, "app.jsonl")
+	curData := `{"time":"2024-01-01T00:00:02Z","n":3}
+not json
+{"n":4}
+{"time":1704067203,"n":5}`
+	if err := os.WriteFile(cur, []byte(curData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &file.FileConfig{
+		Path: "./*.jsonl",
+	}
+	opts := &types.CollectOpts{
+		Since: time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC),
+	}
+	iter, err := file.FileCollect(t.Context(), filepath.Join(tmpdir, "config.toml"), cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv = append(recv, string(ent))
+	}
+
+	wants := []string{
+		`{"time":"2024-01-01T00:00:01Z","n":2}`,
+		`{"time":"2024-01-01T00:00:02Z","n":3}`,
+		`{"n":4}`,
+		`{"time":1704067203,"n":5}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
+
+func TestFileCollectArchive(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	line := func(n int) string {
+		return fmt.Sprintf("{\"time\":\"2024-01-01T00:00:%02dZ\",\"n\":%d}\n", n, n)
+	}
+
+	compress := func(newWriter func(io.Writer) (io.WriteCloser, error), data []byte) []byte {
+		var b bytes.Buffer
+		w, err := newWriter(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return b.Bytes()
+	}
+	gz := func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	}
+	zst := func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w)
+	}
+	xzw := func(w io.Writer) (io.WriteCloser, error) {
+		return xz.NewWriter(w)
+	}
+
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	for _, n := range []int{1, 2} {
+		data := []byte(line(n))
+		if err := tw.WriteHeader(&tar.Header{Name: fmt.Sprintf("app.log.%d", n), Mode: 0o600, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var zipball bytes.Buffer
+	zw := zip.NewWriter(&zipball)
+	w, err := zw.Create("app.log.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(line(4))); err != nil {
+		t.Fatal(err)
+	}
+	// compressed member
+	w, err = zw.Create("app.log.5.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(compress(gz, []byte(line(5)))); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	bz2, err := os.ReadFile("testdata/app.log.6.bz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"app.log", []byte(line(11))},
+		{"app.log.1.gz", compress(gz, []byte(line(10)))},
+		{"app.log.2.zst", compress(zst, []byte(line(9)))},
+		{"app.log.3", []byte(line(7) + line(8))},
+		{"app.log.4.xz", compress(xzw, []byte(line(6)))},
+		{"app.log.5.zip", zipball.Bytes()},
+		{"app.log.6.bz2", bz2},
+		{"app.log.7.tar.gz", compress(gz, tarball.Bytes())},
+	}
+	for _, f := range files {
+		p := filepath.Join(tmpdir, f.name)
+		if err := os.WriteFile(p, f.data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// Modification times don't follow the generations. (e.g. copied)
+		if err := os.Chtimes(p, time.Time{}, time.Unix(0, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &file.FileConfig{
+		Path: filepath.Join(tmpdir, "app.log*"),
+	}
+	opts := &types.CollectOpts{
+		Since: time.Date(2024, 1, 1, 0, 0, 2, 0, time.UTC),
+	}
+	iter, err := file.FileCollect(t.Context(), ".", cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv = append(recv, string(ent))
+	}
+
+	wants := []string{}
+	for n := 2; n <= 11; n++ {
+		wants = append(wants, strings.TrimSuffix(line(n), "\n"))
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
+
+func TestFileCollectTail(t *testing.T) {
+	cx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	tmpdir := t.TempDir()
+	cur := filepath.Join(tmpdir, "app.jsonl")
+	if err := os.WriteFile(cur, []byte("{\"n\":0}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &file.FileConfig{
+		Path: filepath.Join(tmpdir, "*.jsonl"),
+	}
+	opts := &types.CollectOpts{
+		Tail: true,
+	}
+	iter, err := file.FileCollect(cx, ".", cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appendLine := func(path, line string) {
+		fp, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer fp.Close()
+
+		if _, err := fp.WriteString(line); err != nil {
+			t.Error(err)
+		}
+	}
+
+	steps := []func(){
+		func() {
+			// partial line first
+			appendLine(cur, `{"n":`)
+			appendLine(cur, "1}\n")
+		},
+		func() {
+			// rotate by rename
+			if err := os.Rename(cur, filepath.Join(tmpdir, "app.jsonl.1")); err != nil {
+				t.Error(err)
+			}
+			appendLine(cur, "{\"n\":2}\n")
+		},
+		func() {
+			// rotate by truncate
+			if err := os.Truncate(cur, 0); err != nil {
+				t.Error(err)
+			}
+			time.Sleep(100 * time.Millisecond)
+			appendLine(cur, "{\"n\":3}\n")
+		},
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		steps[0]()
+	}()
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv = append(recv, string(ent))
+		if len(recv) == len(steps) {
+			break
+		}
+
+		go steps[len(recv)]()
+	}
+
+	wants := []string{`{"n":1}`, `{"n":2}`, `{"n":3}`}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
+
+func TestFileCollectParser(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	data := `time=2024-01-01T00:00:00Z level=info msg=old
+time=2024-01-01T00:00:01Z level=warn msg="new one"
+not logfmt
+`
+	if err := os.WriteFile(filepath.Join(tmpdir, "app.log"), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &file.FileConfig{
+		Path: "./app.log",
+	}
+	cfg.Parser = "logfmt"
+	cfg.NonJson = "wrap"
+	opts := &types.CollectOpts{
+		Since: time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC),
+	}
+	iter, err := file.FileCollect(t.Context(), filepath.Join(tmpdir, "config.toml"), cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv = append(recv, string(ent))
+	}
+
+	wants := []string{
+		`{"time":"2024-01-01T00:00:01Z","level":"warn","msg":"new one"}`,
+		`{"message":"not logfmt","_raw":true}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
+
+func TestFileCollectUntil(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	data := `{"at":"2024-01-01T00:00:00Z","n":1}
+{"n":2}
+{"at":"2024-01-01T00:00:01Z","n":3}
+{"at":"2024-01-01T00:00:02Z","n":4}
+{"at":"2024-01-01T00:00:00Z","n":5}
+`
+	if err := os.WriteFile(filepath.Join(tmpdir, "app.jsonl"), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &file.FileConfig{
+		Path:           "./app.jsonl",
+		TimestampField: "at",
+	}
+	opts := &types.CollectOpts{
+		Until: time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC),
+	}
+	iter, err := file.FileCollect(t.Context(), filepath.Join(tmpdir, "config.toml"), cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv = append(recv, string(ent))
+	}
+
+	// Stops at the first record after until.
+	wants := []string{
+		`{"at":"2024-01-01T00:00:00Z","n":1}`,
+		`{"n":2}`,
+		`{"at":"2024-01-01T00:00:01Z","n":3}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
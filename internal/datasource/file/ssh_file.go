@@ -0,0 +1,169 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type SshFileConfig struct {
+	// `path` is a glob pattern on the remote host.
+	FileConfig
+	sshclient.Config
+}
+
+func isSafeBracket(expr string) bool {
+	for _, c := range expr {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '!', c == '^', c == '.', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Quote for POSIX shell, leaving glob metacharacters to be expanded by the remote shell.
+func quoteGlob(pattern string) string {
+	var b strings.Builder
+
+	if strings.HasPrefix(pattern, "~/") {
+		b.WriteString("~/")
+		pattern = pattern[2:]
+	}
+
+	literal := ""
+	flush := func() {
+		if literal != "" {
+			b.WriteString(sshclient.Quote(literal))
+			literal = ""
+		}
+	}
+
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*', '?':
+			flush()
+			b.WriteByte(pattern[0])
+			pattern = pattern[1:]
+			continue
+
+		case '[':
+			if end := strings.IndexByte(pattern[1:], ']'); end > 0 && isSafeBracket(pattern[1:end+1]) {
+				flush()
+				b.WriteString(pattern[:end+2])
+				pattern = pattern[end+2:]
+				continue
+			}
+		}
+
+		literal += pattern[:1]
+		pattern = pattern[1:]
+	}
+	flush()
+
+	return b.String()
+}
+
+// Print "$f", decompressed or extracted by its extension.
+const remoteCat = `case "$f" in ` +
+	`*.tar|*.tar.*|*.tgz|*.tbz2|*.txz|*.tzst) tar -xOf "$f" ;; ` +
+	`*.zip) unzip -p "$f" ;; ` +
+	`*.gz) gzip -dc -- "$f" ;; ` +
+	`*.bz2) bzip2 -dc -- "$f" ;; ` +
+	`*.xz) xz -dc -- "$f" ;; ` +
+	`*.zst) zstd -dcq -- "$f" ;; ` +
+	`*) cat -- "$f" ;; ` +
+	`esac`
+
+func tailCommand(pattern string) string {
+	// Files which start matching the pattern later are not followed.
+	return fmt.Sprintf("exec tail -q -n 0 -F -- %s", quoteGlob(pattern))
+}
+
+// Oldest first by the modification time. Ordered again by the rotation generation as local files.
+func listCommand(pattern string) string {
+	return fmt.Sprintf("ls -1tr -d -- %s 2>/dev/null", quoteGlob(pattern))
+}
+
+func catCommand(paths []string) string {
+	quoted := make([]string, 0, len(paths))
+	for _, p := range paths {
+		quoted = append(quoted, sshclient.Quote(p))
+	}
+	return fmt.Sprintf("for f in %s; do %s; echo; done", strings.Join(quoted, " "), remoteCat)
+}
+
+func listRemote(cx context.Context, cfgPath string, cfg *SshFileConfig) ([]string, error) {
+	stdout, onDone, err := sshclient.Start(cx, cfgPath, &cfg.Config, listCommand(cfg.Path))
+	if err != nil {
+		return nil, err
+	}
+	defer onDone()
+
+	b, err := io.ReadAll(stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	ages := []int{}
+	for line := range strings.SplitSeq(string(b), "\n") {
+		if line == "" {
+			continue
+		}
+		paths = append(paths, line)
+		ages = append(ages, len(ages))
+	}
+	return sortRotated(paths, ages), nil
+}
+
+func SshFileCollect(cx context.Context, cfgPath string, cfg *SshFileConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	if cfg.Path == "" {
+		return nil, errors.New("empty path")
+	}
+
+	conv, err := parser.NewConverter(&cfg.FileConfig.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := tailCommand(cfg.Path)
+	if !opts.Tail {
+		paths, err := listRemote(cx, cfgPath, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			return func(yield func(json.RawMessage, error) bool) {}, nil
+		}
+		cmd = catCommand(paths)
+	}
+
+	stdout, onDone, err := sshclient.Start(cx, cfgPath, &cfg.Config, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(json.RawMessage, error) bool) {
+		defer onDone()
+
+		since, until := opts.Since, opts.Until
+		if opts.Tail {
+			since, until = time.Time{}, time.Time{}
+		}
+
+		keep := true
+		readSince(cx, conv, bufio.NewReader(stdout), cfg.timestampFields(), since, until, &keep, yield)
+	}, nil
+}
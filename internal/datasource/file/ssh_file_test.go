@@ -0,0 +1,216 @@
+package file_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/file"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient/sshtest"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+func TestSshFileCollect(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	server := sshtest.NewServer(t)
+
+	tmpdir := filepath.Join(t.TempDir(), "it's a dir")
+	if err := os.Mkdir(tmpdir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	if _, err := gw.Write([]byte(`{"time":"2024-01-01T00:00:00Z","n":0}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	older := filepath.Join(tmpdir, "app.2.jsonl.gz")
+	if err := os.WriteFile(older, gz.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(older, time.Time{}, time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	old := filepath.Join(tmpdir, "app.1.jsonl")
+	if err := os.WriteFile(old, []byte(`{"time":"2024-01-01T00:00:00Z","n":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(old, time.Time{}, time.Unix(60, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	cur := filepath.Join(tmpdir, "app.jsonl")
+	curData := `{"time":"2024-01-01T00:00:01Z","n":2}
+{"n":3}
+`
+	if err := os.WriteFile(cur, []byte(curData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &file.SshFileConfig{
+		FileConfig: file.FileConfig{
+			Path: filepath.Join(tmpdir, "app*.jsonl*"),
+		},
+		Config: server.Config,
+	}
+
+	t.Run("history", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		iter, err := file.SshFileCollect(t.Context(), ".", cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, string(ent))
+		}
+
+		wants := []string{
+			`{"time":"2024-01-01T00:00:00Z","n":0}`,
+			`{"time":"2024-01-01T00:00:00Z","n":1}`,
+			`{"time":"2024-01-01T00:00:01Z","n":2}`,
+			`{"n":3}`,
+		}
+		if !slices.Equal(wants, recv) {
+			t.Fatalf("%#v != %#v", wants, recv)
+		}
+
+		opts.Since = time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC)
+		iter, err = file.SshFileCollect(t.Context(), ".", cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv = []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, string(ent))
+		}
+
+		if !slices.Equal(wants[2:], recv) {
+			t.Fatalf("%#v != %#v", wants[2:], recv)
+		}
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Tail: true,
+		}
+		iter, err := file.SshFileCollect(t.Context(), ".", cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			fp, err := os.OpenFile(cur, os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer fp.Close()
+
+			// Until the remote `tail` starts.
+			for {
+				if _, err := fmt.Fprintln(fp, `{"n":4}`); err != nil {
+					t.Error(err)
+					return
+				}
+				select {
+				case <-done:
+					return
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+		}()
+
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(ent) != `{"n":4}` {
+				t.Fatalf("%s != %s", ent, `{"n":4}`)
+			}
+			break
+		}
+	})
+}
+
+func TestSshFileCollectRotated(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	server := sshtest.NewServer(t)
+	tmpdir := t.TempDir()
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	if _, err := gw.Write([]byte("{\"n\":0}\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modification times are reversed. (e.g. `copytruncate`, compressed later)
+	files := []struct {
+		name    string
+		data    []byte
+		modTime time.Time
+	}{
+		{"app.log", []byte("{\"n\":2}\n"), time.Unix(0, 0)},
+		{"app.log.1", []byte("{\"n\":1}\n"), time.Unix(60, 0)},
+		{"app.log.2.gz", gz.Bytes(), time.Unix(120, 0)},
+	}
+	for _, f := range files {
+		p := filepath.Join(tmpdir, f.name)
+		if err := os.WriteFile(p, f.data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, time.Time{}, f.modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &file.SshFileConfig{
+		FileConfig: file.FileConfig{
+			Path: filepath.Join(tmpdir, "app.log*"),
+		},
+		Config: server.Config,
+	}
+	iter, err := file.SshFileCollect(t.Context(), ".", cfg, &types.CollectOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+		recv = append(recv, string(ent))
+	}
+
+	// Same as local files.
+	wants := []string{`{"n":0}`, `{"n":1}`, `{"n":2}`}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !no_ingest
+
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"iter"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/ingest"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type ingestDatasource struct{}
+
+func (d *ingestDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	return collectReceived(cx, env, opts)
+}
+
+func (d *ingestDatasource) listen(cx context.Context, env *datasourceEnv, w io.Writer) (func() error, error) {
+	// Records are pushed by Ingest.
+	return nil, nil
+}
+
+func init() {
+	registerDatasource("ingest", new(ingestDatasource))
+}
+
+// Append NDJSON records in body to the `ingest` collection.
+func Ingest(cx context.Context, cfg *config.Config, name string, body io.Reader) (*IngestResult, error) {
+	if name == "" {
+		return nil, ErrCollectionNotFound
+	}
+
+	targets, err := receiverTargets(cx, cfg, name, "ingest")
+	if err != nil {
+		return nil, err
+	}
+
+	return ingest.Ingest(body, targets[0].w)
+}
@@ -0,0 +1,44 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+type IngestResult struct {
+	Accepted int `json:"accepted"`
+	Dropped  int `json:"dropped"`
+}
+
+// Copy NDJSON records from r to w. Lines which are not JSON are dropped.
+func Ingest(r io.Reader, w io.Writer) (*IngestResult, error) {
+	result := new(IngestResult)
+
+	br := bufio.NewReader(r)
+	buf := new(bytes.Buffer)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			buf.Reset()
+			if err := json.Compact(buf, line); err != nil {
+				result.Dropped++
+			} else {
+				buf.WriteByte('\n')
+				if _, err := w.Write(buf.Bytes()); err != nil {
+					return result, err
+				}
+				result.Accepted++
+			}
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return result, nil
+			}
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package ingest_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/ingest"
+)
+
+func TestIngest(t *testing.T) {
+	body := `{"n":1}
+{
+not json
+
+{ "n" : 2 }`
+
+	w := new(bytes.Buffer)
+	result, err := ingest.Ingest(strings.NewReader(body), w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Accepted != 2 || result.Dropped != 2 {
+		t.Fatalf("%#v", result)
+	}
+
+	wants := "{\"n\":1}\n{\"n\":2}\n"
+	if w.String() != wants {
+		t.Fatalf("%q != %q", w.String(), wants)
+	}
+}
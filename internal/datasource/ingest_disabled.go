@@ -0,0 +1,14 @@
+//go:build no_ingest
+
+package datasource
+
+import (
+	"context"
+	"io"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+)
+
+func Ingest(cx context.Context, cfg *config.Config, name string, body io.Reader) (*IngestResult, error) {
+	return nil, ErrCollectionNotFound
+}
@@ -0,0 +1,78 @@
+//go:build !no_journald
+
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+	"mime"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/journald"
+	"github.com/ysuzuki-bysystems/seigo/internal/journal"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type journalRemoteDatasource struct{}
+
+func (d *journalRemoteDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	return collectReceived(cx, env, opts)
+}
+
+func (d *journalRemoteDatasource) listen(cx context.Context, env *datasourceEnv, w io.Writer) (func() error, error) {
+	// Records are pushed by ReceiveJournalExport.
+	return nil, nil
+}
+
+func init() {
+	registerDatasource("journal-remote", new(journalRemoteDatasource))
+}
+
+// Append entries in the Journal Export Format to the `journal-remote` collection. If name is empty, to all `journal-remote` collections.
+func ReceiveJournalExport(cx context.Context, cfg *config.Config, name, contentType string, body io.Reader) error {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != journal.ContentTypeExport {
+		return ErrUnsupportedMediaType
+	}
+
+	targets, err := receiverTargets(cx, cfg, name, "journal-remote")
+	if err != nil {
+		return err
+	}
+
+	ws := make([]*journald.RemoteWriter, 0, len(targets))
+	for _, target := range targets {
+		var jcfg journald.JournaldConfig
+		if err := target.env.unmarshalConfig(&jcfg); err != nil {
+			return err
+		}
+		ws = append(ws, journald.NewRemoteWriter(&jcfg, target.w))
+	}
+
+	var errs []error
+	for e, err := range journal.ReadExport(body) {
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		for _, w := range ws {
+			if err := w.WriteEntry(e); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			break
+		}
+	}
+
+	for _, w := range ws {
+		if err := w.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,14 @@
+//go:build no_journald
+
+package datasource
+
+import (
+	"context"
+	"io"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+)
+
+func ReceiveJournalExport(cx context.Context, cfg *config.Config, name, contentType string, body io.Reader) error {
+	return ErrCollectionNotFound
+}
@@ -40,3 +40,33 @@ func (d *sshJournaldDatasource) collect(cx context.Context, env *datasourceEnv,
 func init() {
 	registerDatasource("ssh+journald", new(sshJournaldDatasource))
 }
+
+type journalFilesDatasource struct{}
+
+func (d *journalFilesDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	var cfg journald.JournalFilesConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return journald.JournalFilesCollect(cx, env.path, &cfg, opts)
+}
+
+func init() {
+	registerDatasource("journal-files", new(journalFilesDatasource))
+}
+
+type gatewaydDatasource struct{}
+
+func (d *gatewaydDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	var cfg journald.GatewaydConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return journald.GatewaydCollect(cx, env.path, &cfg, opts)
+}
+
+func init() {
+	registerDatasource("journald+gatewayd", new(gatewaydDatasource))
+}
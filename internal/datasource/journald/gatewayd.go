@@ -0,0 +1,217 @@
+package journald
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+// https://www.freedesktop.org/software/systemd/man/latest/systemd-journal-gatewayd.service.html
+type GatewaydConfig struct {
+	JournaldConfig
+	// e.g. `http://localhost:19531`
+	Url      string `json:"url"`
+	CaFile   string `json:"ca-file"`
+	CertFile string `json:"cert-file"`
+	KeyFile  string `json:"key-file"`
+}
+
+func newGatewaydClient(cfgPath string, cfg *GatewaydConfig) (*http.Client, error) {
+	tlsConfig := &tls.Config{}
+
+	if cfg.CaFile != "" {
+		ca, err := os.ReadFile(resolvePath(cfgPath, cfg.CaFile))
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("invalid ca-file")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(resolvePath(cfgPath, cfg.CertFile), resolvePath(cfgPath, cfg.KeyFile))
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
+
+// gatewayd has no disjunction. Only one `match` table, and fields must not be repeated.
+func gatewaydQuery(cfg *GatewaydConfig, follow bool) (string, error) {
+	params := make([]string, 0)
+	if follow {
+		params = append(params, "follow")
+	}
+
+	groups, err := cfg.matchGroups(func(boot string) (*matchCondition, error) {
+		if boot == "0" {
+			params = append(params, "boot")
+			return nil, nil
+		}
+		id, err := bootId(boot)
+		if err != nil {
+			return nil, err
+		}
+		return &matchCondition{field: "_BOOT_ID", values: []string{id}}, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(groups) > 1 {
+		return "", errors.New("multiple match tables are not supported by gatewayd")
+	}
+
+	fields := make(map[string]bool)
+	for _, c := range groups[0] {
+		if fields[c.field] {
+			return "", fmt.Errorf("%s is matched more than once, not supported by gatewayd", c.field)
+		}
+		fields[c.field] = true
+
+		// Values for the same field are OR-ed.
+		for _, val := range c.values {
+			params = append(params, fmt.Sprintf("%s=%s", url.QueryEscape(c.field), url.QueryEscape(val)))
+		}
+	}
+	return strings.Join(params, "&"), nil
+}
+
+// Ends with EOF instead of an error on cancellation.
+type cancelableReader struct {
+	cx context.Context
+	r  io.Reader
+}
+
+func (r *cancelableReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && r.cx.Err() != nil {
+		return n, io.EOF
+	}
+	return n, err
+}
+
+func entriesRequest(cx context.Context, cfg *GatewaydConfig, follow bool, entries string) (*http.Request, error) {
+	u := fmt.Sprintf("%s/entries", strings.TrimSuffix(cfg.Url, "/"))
+	q, err := gatewaydQuery(cfg, follow)
+	if err != nil {
+		return nil, err
+	}
+	if q != "" {
+		u = fmt.Sprintf("%s?%s", u, q)
+	}
+
+	req, err := http.NewRequestWithContext(cx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if entries != "" {
+		// https://www.freedesktop.org/software/systemd/man/latest/systemd-journal-gatewayd.service.html#Range%20header
+		req.Header.Set("Range", fmt.Sprintf("entries=%s", entries))
+	}
+	return req, nil
+}
+
+func getEntries(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("/entries: %s %s", resp.Status, msg)
+	}
+	return resp, nil
+}
+
+// Cursor of the last matching entry. Empty if none.
+func lastCursor(cx context.Context, client *http.Client, cfg *GatewaydConfig) (string, error) {
+	req, err := entriesRequest(cx, cfg, false, ":-1:1")
+	if err != nil {
+		return "", err
+	}
+	resp, err := getEntries(client, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var entry journaldRecord
+	if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", nil
+		}
+		return "", err
+	}
+	return entry.get("__CURSOR"), nil
+}
+
+func GatewaydCollect(cx context.Context, cfgPath string, cfg *GatewaydConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	if cfg.Url == "" {
+		return nil, errors.New("empty url")
+	}
+
+	client, err := newGatewaydClient(cfgPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// gatewayd cannot seek by time. History is read from the head, filtered by `__REALTIME_TIMESTAMP`, and ends at until.
+	since, until := opts.Since, opts.Until
+	after := validCursor(opts.After)
+	if opts.Tail {
+		since, until = time.Time{}, time.Time{}
+
+		if after == "" {
+			// Follow after the last existing entry. From the head if none.
+			after, err = lastCursor(cx, client, cfg)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	entries := ""
+	if after != "" {
+		// Skip the entry of the cursor.
+		entries = fmt.Sprintf("%s:1:", after)
+	}
+	req, err := entriesRequest(cx, cfg, opts.Tail, entries)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := getEntries(client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	onDone := func() {
+		_ = resp.Body.Close()
+	}
+	body := &cancelableReader{cx: cx, r: resp.Body}
+	return iterRecords(&cfg.JournaldConfig, since, until, body, onDone, opts.Cursor), nil
+}
@@ -0,0 +1,187 @@
+package journald_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/journald"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+func gatewaydEntry(t time.Time, message string, partial bool) map[string]string {
+	e := map[string]string{
+		"__REALTIME_TIMESTAMP": fmt.Sprint(t.UnixMicro()),
+		"MESSAGE":              message,
+	}
+	if partial {
+		e["CONTAINER_PARTIAL_MESSAGE"] = "true"
+	}
+	return e
+}
+
+func TestGatewaydCollect(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /entries", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" || r.URL.Query().Get("_SYSTEMD_UNIT") != "app.service" {
+			http.Error(w, r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+
+		enc := json.NewEncoder(w)
+		if !r.URL.Query().Has("follow") {
+			if r.Header.Get("Range") == "entries=:-1:1" {
+				e := gatewaydEntry(base.Add(2*time.Second), `{"n":2}`, false)
+				e["__CURSOR"] = "s=1;i=4"
+				_ = enc.Encode(e)
+				return
+			}
+
+			for _, e := range []map[string]string{
+				gatewaydEntry(base, `{"n":1}`, false),
+				gatewaydEntry(base.Add(time.Second), `{"data":"loooooooong`, true),
+				gatewaydEntry(base.Add(time.Second), `-message"}`, false),
+				gatewaydEntry(base.Add(2*time.Second), `{"n":2}`, false),
+			} {
+				_ = enc.Encode(e)
+			}
+			return
+		}
+
+		if r.Header.Get("Range") != "entries=s=1;i=4:1:" {
+			http.Error(w, r.Header.Get("Range"), http.StatusBadRequest)
+			return
+		}
+		// Not filtered by the local clock.
+		_ = enc.Encode(gatewaydEntry(base.Add(3*time.Second), `{"n":"new"}`, false))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	cfg := &journald.GatewaydConfig{
+		JournaldConfig: journald.JournaldConfig{
+			Match: []map[string]string{
+				{"_SYSTEMD_UNIT": "app.service"},
+			},
+		},
+		Url: server.URL,
+	}
+
+	t.Run("history", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Since: base.Add(time.Second),
+		}
+		iter, err := journald.GatewaydCollect(t.Context(), ".", cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, string(ent))
+		}
+
+		wants := []string{
+			`{"data":"loooooooong-message"}`,
+			`{"n":2}`,
+		}
+		if !slices.Equal(wants, recv) {
+			t.Fatalf("%#v != %#v", wants, recv)
+		}
+	})
+
+	t.Run("until", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Until: base.Add(time.Second),
+		}
+		iter, err := journald.GatewaydCollect(t.Context(), ".", cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, string(ent))
+		}
+
+		wants := []string{
+			`{"n":1}`,
+			`{"data":"loooooooong-message"}`,
+		}
+		if !slices.Equal(wants, recv) {
+			t.Fatalf("%#v != %#v", wants, recv)
+		}
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Tail: true,
+		}
+		iter, err := journald.GatewaydCollect(t.Context(), ".", cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(ent) != `{"n":"new"}` {
+				t.Fatalf("%s != %s", ent, `{"n":"new"}`)
+			}
+			break
+		}
+	})
+}
+
+func TestGatewaydCollectSelection(t *testing.T) {
+	queries := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.RawQuery
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &journald.GatewaydConfig{
+		JournaldConfig: journald.JournaldConfig{
+			Unit:     []string{"app"},
+			Priority: "err",
+			Boot:     "0",
+			Match: []map[string]string{
+				{"CONTAINER_NAME": "web"},
+			},
+		},
+		Url: server.URL,
+	}
+
+	iter, err := journald.GatewaydCollect(t.Context(), ".", cfg, &types.CollectOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range iter {
+	}
+
+	wants := "boot&_SYSTEMD_UNIT=app.service&PRIORITY=0&PRIORITY=1&PRIORITY=2&PRIORITY=3&CONTAINER_NAME=web"
+	if q := <-queries; q != wants {
+		t.Fatalf("%s != %s", q, wants)
+	}
+
+	cfg.Match = append(cfg.Match, map[string]string{"_PID": "1"})
+	if _, err := journald.GatewaydCollect(t.Context(), ".", cfg, &types.CollectOpts{}); err == nil {
+		t.Fatal("must fail")
+	}
+}
@@ -0,0 +1,354 @@
+package journald
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/journal"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+var journalFilesPollInterval = time.Second
+
+type JournalFilesConfig struct {
+	JournaldConfig
+	// Directory containing `*.journal` files. e.g. `/var/log/journal/<machine-id>`
+	Directory string `json:"directory"`
+}
+
+// Same as journalctl. Groups are OR-ed, and conditions in a group are AND-ed.
+type matcher [][]*matchCondition
+
+func newMatcher(cfg *JournaldConfig) (matcher, error) {
+	return cfg.matchGroups(func(boot string) (*matchCondition, error) {
+		id, err := bootId(boot)
+		if err != nil {
+			return nil, err
+		}
+		return &matchCondition{field: "_BOOT_ID", values: []string{id}}, nil
+	})
+}
+
+func (c *matchCondition) match(e *journal.Entry) bool {
+	for _, field := range e.Fields {
+		if field.Name == c.field && slices.Contains(c.values, string(field.Value)) {
+			return true
+		}
+	}
+	if c.field == "_BOOT_ID" {
+		// Also in the entry object.
+		return slices.Contains(c.values, hex.EncodeToString(e.BootId[:]))
+	}
+	return false
+}
+
+func (m matcher) match(e *journal.Entry) bool {
+	for _, group := range m {
+		matched := true
+		for _, c := range group {
+			if !c.match(e) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+type journalCursor struct {
+	file    *journal.File
+	offsets []uint64
+	head    time.Time
+}
+
+func (c *journalCursor) advance() error {
+	if len(c.offsets) == 0 {
+		return nil
+	}
+
+	t, err := c.file.Realtime(c.offsets[0])
+	if err != nil {
+		return err
+	}
+	c.head = t
+	return nil
+}
+
+type journalFile struct {
+	*journal.File
+	// Identifies the file across renames.
+	info os.FileInfo
+	// Number of entries already read.
+	read int
+}
+
+// Journal files in the directory. They are kept open while tailing, and only the entries appended are read.
+type journalFiles struct {
+	dir   string
+	files map[[16]byte]*journalFile
+}
+
+func newJournalFiles(dir string) *journalFiles {
+	return &journalFiles{
+		dir:   dir,
+		files: make(map[[16]byte]*journalFile),
+	}
+}
+
+func (j *journalFiles) lookup(fi os.FileInfo) *journalFile {
+	for _, f := range j.files {
+		if os.SameFile(f.info, fi) {
+			return f
+		}
+	}
+	return nil
+}
+
+// Open new files (e.g. created by rotation), refresh opened ones and close removed ones.
+func (j *journalFiles) scan() error {
+	paths := make([]string, 0)
+	for _, pattern := range []string{"*.journal", "*.journal~"} {
+		p, err := filepath.Glob(filepath.Join(j.dir, pattern))
+		if err != nil {
+			return err
+		}
+		paths = append(paths, p...)
+	}
+
+	found := make(map[[16]byte]bool)
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// rotated away
+				continue
+			}
+			return err
+		}
+
+		if f := j.lookup(fi); f != nil {
+			// Same or renamed (e.g. archived).
+			if !found[f.FileId()] {
+				if err := f.Refresh(); err != nil {
+					return err
+				}
+			}
+			found[f.FileId()] = true
+			continue
+		}
+
+		file, err := journal.Open(p)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, journal.ErrInvalid) {
+				// rotated away or not initialized yet
+				continue
+			}
+			return err
+		}
+		if _, ok := j.files[file.FileId()]; ok {
+			// Copied.
+			_ = file.Close()
+			continue
+		}
+		j.files[file.FileId()] = &journalFile{File: file, info: fi}
+		found[file.FileId()] = true
+	}
+
+	for id, f := range j.files {
+		if !found[id] {
+			_ = f.Close()
+			delete(j.files, id)
+		}
+	}
+
+	return nil
+}
+
+// Mark existing entries as read.
+func (j *journalFiles) skip() error {
+	for _, f := range j.files {
+		offsets, err := f.EntryOffsets()
+		if err != nil {
+			return err
+		}
+		f.read = len(offsets)
+	}
+	return nil
+}
+
+// Yield entries which are not read yet, ordered by realtime. Entries up to `after` are skipped if not nil.
+// Returns false if the iteration is stopped.
+func (j *journalFiles) read(since, until time.Time, after *journal.Position, m matcher, yield func(journaldRecord, error) bool) bool {
+	fail := func(err error) bool {
+		yield(nil, err)
+		return false
+	}
+
+	cursors := make([]*journalCursor, 0, len(j.files))
+	for _, f := range j.files {
+		offsets, err := f.EntryOffsets()
+		if err != nil {
+			return fail(err)
+		}
+
+		n := f.read
+		f.read = len(offsets)
+		if n >= len(offsets) {
+			continue
+		}
+
+		c := &journalCursor{file: f.File, offsets: offsets[n:]}
+		if err := c.advance(); err != nil {
+			return fail(err)
+		}
+		cursors = append(cursors, c)
+	}
+
+	for {
+		var next *journalCursor
+		for _, c := range cursors {
+			if len(c.offsets) == 0 {
+				continue
+			}
+			if next == nil || c.head.Before(next.head) {
+				next = c
+			}
+		}
+		if next == nil {
+			return true
+		}
+
+		if !until.IsZero() && next.head.After(until) {
+			return true
+		}
+
+		offset := next.offsets[0]
+		next.offsets = next.offsets[1:]
+		head := next.head
+		if err := next.advance(); err != nil {
+			return fail(err)
+		}
+
+		if head.Before(since) {
+			continue
+		}
+		// Without reading the entry. Entries before the time of the cursor are regarded as read.
+		if after != nil && head.Before(after.Realtime) {
+			continue
+		}
+
+		e, err := next.file.Entry(offset)
+		if err != nil {
+			return fail(err)
+		}
+		if after != nil && !e.After(after) {
+			continue
+		}
+		if !m.match(e) {
+			continue
+		}
+
+		fields, err := e.JSONFields()
+		if err != nil {
+			return fail(err)
+		}
+		if !yield(fields, nil) {
+			return false
+		}
+	}
+}
+
+func (j *journalFiles) close() {
+	for id, f := range j.files {
+		_ = f.Close()
+		delete(j.files, id)
+	}
+}
+
+func JournalFilesCollect(cx context.Context, cfgPath string, cfg *JournalFilesConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	if cfg.Directory == "" {
+		return nil, errors.New("empty directory")
+	}
+
+	dir := resolvePath(cfgPath, cfg.Directory)
+	if fi, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, errors.New("not a directory")
+	}
+
+	m, err := newMatcher(&cfg.JournaldConfig)
+	if err != nil {
+		return nil, err
+	}
+	var after *journal.Position
+	if opts.After != "" {
+		// Starts over if invalid.
+		after, _ = journal.ParseCursor(opts.After)
+	}
+
+	files := newJournalFiles(dir)
+	if opts.Tail && after == nil {
+		// Skip existing entries.
+		if err := files.scan(); err != nil {
+			files.close()
+			return nil, err
+		}
+		if err := files.skip(); err != nil {
+			files.close()
+			return nil, err
+		}
+	}
+
+	entries := func(yield func(journaldRecord, error) bool) {
+		if !opts.Tail || after != nil {
+			if err := files.scan(); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+
+		if !opts.Tail {
+			files.read(opts.Since, opts.Until, after, m, yield)
+			return
+		}
+
+		if after != nil {
+			// Entries written while disconnected.
+			if !files.read(time.Time{}, time.Time{}, after, m, yield) {
+				return
+			}
+		}
+
+		ticker := time.NewTicker(journalFilesPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-cx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := files.scan(); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !files.read(time.Time{}, time.Time{}, nil, m, yield) {
+				return
+			}
+		}
+	}
+
+	return convertRecords(&cfg.JournaldConfig, time.Time{}, time.Time{}, entries, files.close, opts.Cursor), nil
+}
@@ -0,0 +1,206 @@
+package journald_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/journald"
+	"github.com/ysuzuki-bysystems/seigo/internal/journal/journaltest"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+func TestJournalFilesCollect(t *testing.T) {
+	tmpdir := t.TempDir()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	journaltest.Write(t, filepath.Join(tmpdir, "system@0001.journal"), &journaltest.Options{FileId: [16]byte{1}}, []journaltest.Entry{
+		{Realtime: base, Fields: []string{`MESSAGE={"n":1}`, "_SYSTEMD_UNIT=app.service"}},
+		{Realtime: base.Add(2 * time.Second), Fields: []string{`MESSAGE={"n":3}`, "_SYSTEMD_UNIT=app.service"}},
+	})
+	journaltest.Write(t, filepath.Join(tmpdir, "system.journal"), &journaltest.Options{FileId: [16]byte{2}, Compact: true, Compression: "zstd"}, []journaltest.Entry{
+		{Realtime: base.Add(time.Second), Fields: []string{`MESSAGE={"n":2}`, "_SYSTEMD_UNIT=app.service"}},
+		{Realtime: base.Add(3 * time.Second), Fields: []string{`MESSAGE={"n":4}`, "_SYSTEMD_UNIT=other.service"}},
+		{Realtime: base.Add(4 * time.Second), Fields: []string{`MESSAGE={"data":"loooooooong`, "CONTAINER_PARTIAL_MESSAGE=true", "_SYSTEMD_UNIT=app.service"}},
+		{Realtime: base.Add(4 * time.Second), Fields: []string{`MESSAGE=-message"}`, "_SYSTEMD_UNIT=app.service"}},
+	})
+
+	cfg := &journald.JournalFilesConfig{
+		JournaldConfig: journald.JournaldConfig{
+			Match: []map[string]string{
+				{"_SYSTEMD_UNIT": "app.service"},
+			},
+		},
+		Directory: ".",
+	}
+	cfgPath := filepath.Join(tmpdir, "config.toml")
+
+	t.Run("history", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Since: base.Add(time.Second),
+		}
+		iter, err := journald.JournalFilesCollect(t.Context(), cfgPath, cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, string(ent))
+		}
+
+		wants := []string{
+			`{"n":2}`,
+			`{"n":3}`,
+			`{"data":"loooooooong-message"}`,
+		}
+		if !slices.Equal(wants, recv) {
+			t.Fatalf("%#v != %#v", wants, recv)
+		}
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Tail: true,
+		}
+		iter, err := journald.JournalFilesCollect(t.Context(), cfgPath, cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Rotated. The active file is archived and a new file is created.
+		if err := os.Rename(filepath.Join(tmpdir, "system.journal"), filepath.Join(tmpdir, "system@0002.journal")); err != nil {
+			t.Fatal(err)
+		}
+		journaltest.Write(t, filepath.Join(tmpdir, "system.journal"), &journaltest.Options{FileId: [16]byte{3}}, []journaltest.Entry{
+			{Realtime: base.Add(5 * time.Second), Fields: []string{`MESSAGE={"n":5}`, "_SYSTEMD_UNIT=app.service"}},
+		})
+
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(ent) != `{"n":5}` {
+				t.Fatalf("%s != %s", ent, `{"n":5}`)
+			}
+			break
+		}
+	})
+}
+
+func TestJournalFilesCollectSelection(t *testing.T) {
+	tmpdir := t.TempDir()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	journaltest.Write(t, filepath.Join(tmpdir, "system.journal"), &journaltest.Options{FileId: [16]byte{1}}, []journaltest.Entry{
+		{Realtime: base, Fields: []string{`MESSAGE={"n":1}`, "_SYSTEMD_UNIT=app.service", "PRIORITY=3"}},
+		{Realtime: base.Add(time.Second), Fields: []string{`MESSAGE={"n":2}`, "_SYSTEMD_UNIT=app.service", "PRIORITY=6"}},
+		{Realtime: base.Add(2 * time.Second), Fields: []string{`MESSAGE={"n":3}`, "_SYSTEMD_UNIT=other.service", "_PID=1", "PRIORITY=4"}},
+		{Realtime: base.Add(3 * time.Second), Fields: []string{`MESSAGE={"n":4}`, "_SYSTEMD_UNIT=other.service", "_PID=2", "PRIORITY=4"}},
+		{Realtime: base.Add(4 * time.Second), Fields: []string{`MESSAGE={"n":5}`, "_SYSTEMD_UNIT=app.service", "PRIORITY=0"}},
+	})
+
+	cfg := &journald.JournalFilesConfig{
+		JournaldConfig: journald.JournaldConfig{
+			Priority: "warning",
+			Match: []map[string]string{
+				{"_SYSTEMD_UNIT": "app.service"},
+				{"_SYSTEMD_UNIT": "other.service", "_PID": "1"},
+			},
+		},
+		Directory: ".",
+	}
+	cfgPath := filepath.Join(tmpdir, "config.toml")
+
+	opts := &types.CollectOpts{
+		Until: base.Add(3 * time.Second),
+	}
+	iter, err := journald.JournalFilesCollect(t.Context(), cfgPath, cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+		recv = append(recv, string(ent))
+	}
+
+	wants := []string{
+		`{"n":1}`,
+		`{"n":3}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+
+	cfg.Boot = "-1"
+	if _, err := journald.JournalFilesCollect(t.Context(), cfgPath, cfg, opts); err == nil {
+		t.Fatal("must fail")
+	}
+}
+
+func TestJournalFilesCollectAfter(t *testing.T) {
+	tmpdir := t.TempDir()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	journaltest.Write(t, filepath.Join(tmpdir, "system.journal"), &journaltest.Options{FileId: [16]byte{1}}, []journaltest.Entry{
+		{Realtime: base, Fields: []string{`MESSAGE={"n":1}`}},
+		{Realtime: base.Add(time.Second), Fields: []string{`MESSAGE={"n":2}`}},
+		{Realtime: base.Add(2 * time.Second), Fields: []string{`MESSAGE={"n":3}`}},
+	})
+
+	cfg := &journald.JournalFilesConfig{
+		Directory: ".",
+	}
+	cfgPath := filepath.Join(tmpdir, "config.toml")
+
+	collect := func(opts *types.CollectOpts) ([]string, []string) {
+		cursors := []string{}
+		opts.OnCursor = func(cursor string) {
+			cursors = append(cursors, cursor)
+		}
+
+		iter, err := journald.JournalFilesCollect(t.Context(), cfgPath, cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, string(ent))
+			if opts.Tail && len(recv) == 2 {
+				break
+			}
+		}
+		return recv, cursors
+	}
+
+	recv, cursors := collect(&types.CollectOpts{})
+	if len(recv) != 3 || len(cursors) != 3 {
+		t.Fatalf("%#v %#v", recv, cursors)
+	}
+
+	recv, _ = collect(&types.CollectOpts{After: cursors[0]})
+	wants := []string{`{"n":2}`, `{"n":3}`}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+
+	// Entries written while disconnected.
+	recv, _ = collect(&types.CollectOpts{Tail: true, After: cursors[0]})
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
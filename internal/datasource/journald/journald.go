@@ -5,21 +5,28 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"iter"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+	"github.com/ysuzuki-bysystems/seigo/internal/proc"
+	"github.com/ysuzuki-bysystems/seigo/internal/record"
 	"github.com/ysuzuki-bysystems/seigo/internal/types"
 )
 
-func resolveBin(cfgPath, target string) string {
-	// relative path or not
-	if !strings.HasPrefix(target, ".") {
+func resolvePath(cfgPath, target string) string {
+	if strings.HasPrefix(target, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			target = filepath.Join(home, target[2:])
+		}
+	}
+
+	if filepath.IsAbs(target) {
 		return target
 	}
 
@@ -27,54 +34,205 @@ func resolveBin(cfgPath, target string) string {
 	return filepath.Join(dir, target)
 }
 
-type journaldRecord struct {
-	Message string `json:"MESSAGE"`
+// A record of `journalctl --output=json`.
+// Values are strings, arrays of bytes for binary values, arrays of them for duplicated fields or null for too large ones.
+type journaldRecord map[string]json.RawMessage
+
+func fieldValue(raw json.RawMessage) (string, bool) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, true
+	}
+
+	var bin []byte
+	var nums []int
+	if err := json.Unmarshal(raw, &nums); err == nil {
+		for _, n := range nums {
+			bin = append(bin, byte(n))
+		}
+		return string(bin), true
+	}
+
+	// Duplicated. The first one.
+	var values []json.RawMessage
+	if err := json.Unmarshal(raw, &values); err == nil && len(values) > 0 {
+		return fieldValue(values[0])
+	}
 
-	// https://docs.docker.com/engine/logging/drivers/journald/
-	// > A field that flags log integrity. Improve logging of long log lines.
-	// `"true"` or else
-	ContainerPartialMessage string `json:"CONTAINER_PARTIAL_MESSAGE"`
+	return "", false
+}
+
+func (r journaldRecord) get(name string) string {
+	v, _ := fieldValue(r[name])
+	return v
 }
 
 type JournaldConfig struct {
 	NoDockerAware bool                `json:"no-docker-aware"`
 	Match         []map[string]string `json:"match"`
 	JournalctlCmd string              `json:"journalctl-cmd"`
+	// Same as the journalctl options. e.g. `unit = ["nginx"]`, `priority = "warning"`, `boot = "0"`
+	Unit       []string `json:"unit"`
+	UserUnit   []string `json:"user-unit"`
+	Identifier []string `json:"identifier"`
+	Priority   string   `json:"priority"`
+	Boot       string   `json:"boot"`
+	// How `MESSAGE` is converted into a record.
+	// In addition to the parser ones, `non-json = "fields"` builds records from the journald fields.
+	parser.Config
+	// journald fields added to each record. e.g. `["_SYSTEMD_UNIT", "_HOSTNAME"]`
+	Fields []string `json:"fields"`
+	// Default: `_journal`
+	FieldsKey string `json:"fields-key"`
+	// Add the fields to the record itself, instead of under `fields-key`.
+	MergeFields bool `json:"merge-fields"`
+}
+
+func (c *JournaldConfig) projection(entry journaldRecord) map[string]any {
+	result := make(map[string]any)
+	for _, name := range c.Fields {
+		// Same as get. e.g. the first one of duplicated fields.
+		if v, ok := fieldValue(entry[name]); ok {
+			result[name] = v
+		}
+	}
+	return result
+}
+
+func newRecordConverter(cfg *JournaldConfig) (func(entry journaldRecord, message []byte) (json.RawMessage, error), error) {
+	pcfg := cfg.Config
+	fromFields := pcfg.NonJson == "fields"
+	if fromFields {
+		pcfg.NonJson = "drop"
+	}
+
+	conv, err := parser.NewConverter(&pcfg)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldsKey := cfg.FieldsKey
+	if fieldsKey == "" {
+		fieldsKey = "_journal"
+	}
+
+	// Returns nil if dropped.
+	return func(entry journaldRecord, message []byte) (json.RawMessage, error) {
+		raw, err := conv.Convert(message)
+		if err != nil {
+			return nil, err
+		}
+
+		if raw == nil {
+			if !fromFields {
+				return nil, nil
+			}
+
+			var fields map[string]any
+			if len(cfg.Fields) == 0 {
+				fields = make(map[string]any)
+				for name := range entry {
+					fields[name] = entry.get(name)
+				}
+			} else {
+				fields = cfg.projection(entry)
+			}
+			// Reassembled.
+			fields["MESSAGE"] = string(message)
+			return json.Marshal(fields)
+		}
+
+		if len(cfg.Fields) == 0 {
+			return raw, nil
+		}
+		if cfg.MergeFields {
+			return record.Merge(raw, cfg.projection(entry))
+		}
+		return record.Merge(raw, map[string]any{fieldsKey: cfg.projection(entry)})
+	}, nil
 }
 
-func iterRecords(cfg *JournaldConfig, stdout io.Reader, onDone func()) iter.Seq2[json.RawMessage, error] {
+// Decode the output of `journalctl --output=json`.
+func decodeRecords(r io.Reader) iter.Seq2[journaldRecord, error] {
+	return func(yield func(journaldRecord, error) bool) {
+		dec := json.NewDecoder(bufio.NewReader(r))
+		for {
+			var entry journaldRecord
+			if err := dec.Decode(&entry); err != nil {
+				if !errors.Is(err, io.EOF) {
+					yield(nil, err)
+				}
+				return
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Records before `since` are skipped, and records after `until` end the iteration, in case the source cannot filter them.
+// cursor is called with `__CURSOR` of each record, if not nil.
+func iterRecords(cfg *JournaldConfig, since, until time.Time, stdout io.Reader, onDone func(), cursor func(string)) iter.Seq2[json.RawMessage, error] {
+	return convertRecords(cfg, since, until, decodeRecords(stdout), onDone, cursor)
+}
+
+// Same as iterRecords, from the decoded entries.
+func convertRecords(cfg *JournaldConfig, since, until time.Time, entries iter.Seq2[journaldRecord, error], onDone func(), cursor func(string)) iter.Seq2[json.RawMessage, error] {
 	return func(yield func(json.RawMessage, error) bool) {
 		defer onDone()
 
+		convert, err := newRecordConverter(cfg)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
 		var buf []byte
-		var record journaldRecord
-		dec := json.NewDecoder(bufio.NewReader(stdout))
-		for {
-			if err := dec.Decode(&record); err != nil {
-				if errors.Is(err, io.EOF) {
-					break
-				}
+		var last journaldRecord
+		for entry, err := range entries {
+			if err != nil {
 				yield(nil, err)
 				return
 			}
+			last = entry
 
+			message := entry.get("MESSAGE")
 			if buf == nil {
-				buf = []byte(record.Message)
+				buf = []byte(message)
 			} else {
-				buf = append(buf, []byte(record.Message)...)
+				buf = append(buf, []byte(message)...)
 			}
-			if !cfg.NoDockerAware && record.ContainerPartialMessage == "true" {
+			// https://docs.docker.com/engine/logging/drivers/journald/
+			// > A field that flags log integrity. Improve logging of long log lines.
+			if !cfg.NoDockerAware && entry.get("CONTAINER_PARTIAL_MESSAGE") == "true" {
 				continue
 			}
+			// Microseconds since the epoch.
+			if usec, err := strconv.ParseInt(entry.get("__REALTIME_TIMESTAMP"), 10, 64); err == nil {
+				if !until.IsZero() && time.UnixMicro(usec).After(until) {
+					return
+				}
+				if !since.IsZero() && time.UnixMicro(usec).Before(since) {
+					buf = nil
+					continue
+				}
+			}
 
-			var raw json.RawMessage
-			err := json.Unmarshal(buf, &raw)
+			raw, err := convert(entry, buf)
 			buf = nil
 			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if raw == nil {
 				// drop & skip
 				continue
 			}
 
+			if cursor != nil {
+				cursor(entry.get("__CURSOR"))
+			}
 			if !yield(raw, nil) {
 				return
 			}
@@ -84,11 +242,18 @@ func iterRecords(cfg *JournaldConfig, stdout io.Reader, onDone func()) iter.Seq2
 			return
 		}
 
-		var raw json.RawMessage
-		if err := json.Unmarshal(buf, &raw); err != nil {
+		raw, err := convert(last, buf)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if raw == nil {
 			// drop
 			return
 		}
+		if cursor != nil {
+			cursor(last.get("__CURSOR"))
+		}
 		yield(raw, nil)
 
 	}
@@ -99,41 +264,14 @@ var journalctl = "journalctl"
 func JournaldCollect(cx context.Context, cfgPath string, cfg *JournaldConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
 	program := journalctl
 	if cfg.JournalctlCmd != "" {
-		program = resolveBin(cfgPath, cfg.JournalctlCmd)
-	}
-
-	args := []string{
-		"--output=json",
-	}
-	if opts.Tail {
-		args = append(args, "--follow")
-	} else {
-		args = append(args, fmt.Sprintf("--since=%s", opts.Since.Format(time.RFC3339)))
-	}
-	for _, m := range cfg.Match {
-		// TODO sorted
-		for key, val := range m {
-			args = append(args, fmt.Sprintf("%s=%s", key, val))
-		}
+		program = proc.ResolveBin(cfgPath, cfg.JournalctlCmd)
 	}
 
-	cmd := exec.CommandContext(cx, program, args...)
-	cmd.Cancel = func() error {
-		return cmd.Process.Signal(os.Interrupt)
-	}
-	cmd.Stdin = nil
-	cmd.Stderr = os.Stderr
-	stdout, err := cmd.StdoutPipe()
+	cmd := proc.Command(cx, program, journalctlArgs(cfg, opts)...)
+	stdout, onDone, err := proc.Start(cmd)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := cmd.Start(); err != nil {
-		return nil, err
-	}
-
-	onDone := func() {
-		_, _ = cmd.Process.Wait()
-	}
-	return iterRecords(cfg, stdout, onDone), nil
+	return iterRecords(cfg, time.Time{}, time.Time{}, stdout, onDone, opts.Cursor), nil
 }
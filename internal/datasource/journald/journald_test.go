@@ -48,3 +48,127 @@ func TestJournaldCollect(t *testing.T) {
 		t.Fatalf("%#v != %#v", wants, recv)
 	}
 }
+
+func TestJournaldCollectSelection(t *testing.T) {
+	dummyCfgPath := "./testdata/config.toml" // not exists
+	cfg := &journald.JournaldConfig{
+		Unit:       []string{"nginx", "app.service"},
+		Identifier: []string{"kernel"},
+		Priority:   "warning",
+		Boot:       "0",
+		Match: []map[string]string{
+			{
+				"CONTAINER_NAME": "web",
+				"CONTAINER_ID":   "abc",
+			},
+			{
+				"_PID": "1",
+			},
+		},
+
+		JournalctlCmd: "./journalctl.sh",
+	}
+	opts := &types.CollectOpts{
+		Since: time.Unix(0, 0).UTC(),
+		Until: time.Unix(60, 0).UTC(),
+	}
+	iter, err := journald.JournaldCollect(t.Context(), dummyCfgPath, cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv = append(recv, string(ent))
+	}
+
+	wants := []string{
+		`{"arg":"--output=json"}`,
+		`{"arg":"--since=1970-01-01T00:00:00Z"}`,
+		`{"arg":"--until=1970-01-01T00:01:00Z"}`,
+		`{"arg":"--unit=nginx"}`,
+		`{"arg":"--unit=app.service"}`,
+		`{"arg":"--identifier=kernel"}`,
+		`{"arg":"--priority=warning"}`,
+		`{"arg":"--boot=0"}`,
+		`{"arg":"CONTAINER_ID=abc"}`,
+		`{"arg":"CONTAINER_NAME=web"}`,
+		`{"arg":"+"}`,
+		`{"arg":"_PID=1"}`,
+		`{"data":"loooooooong-message"}`,
+	}
+
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
+
+func TestJournaldCollectAfter(t *testing.T) {
+	dummyCfgPath := "./testdata/config.toml" // not exists
+	cfg := &journald.JournaldConfig{
+		JournalctlCmd: "./journalctl.sh",
+	}
+
+	cases := []struct {
+		opts  *types.CollectOpts
+		wants []string
+	}{
+		{
+			opts: &types.CollectOpts{
+				Since: time.Unix(0, 0).UTC(),
+				After: "s=0123;i=1f;b=ab;m=2;t=5f;x=9",
+			},
+			wants: []string{
+				`{"arg":"--output=json"}`,
+				`{"arg":"--after-cursor=s=0123;i=1f;b=ab;m=2;t=5f;x=9"}`,
+				`{"data":"loooooooong-message"}`,
+			},
+		},
+		{
+			opts: &types.CollectOpts{
+				Tail:  true,
+				After: "s=0123;i=1f;b=ab;m=2;t=5f;x=9",
+			},
+			wants: []string{
+				`{"arg":"--output=json"}`,
+				`{"arg":"--follow"}`,
+				`{"arg":"--after-cursor=s=0123;i=1f;b=ab;m=2;t=5f;x=9"}`,
+				`{"data":"loooooooong-message"}`,
+			},
+		},
+		{
+			// Invalid. Ignored.
+			opts: &types.CollectOpts{
+				Tail:  true,
+				After: "$(reboot)",
+			},
+			wants: []string{
+				`{"arg":"--output=json"}`,
+				`{"arg":"--follow"}`,
+				`{"data":"loooooooong-message"}`,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		iter, err := journald.JournaldCollect(t.Context(), dummyCfgPath, cfg, c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recv := []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recv = append(recv, string(ent))
+		}
+
+		if !slices.Equal(c.wants, recv) {
+			t.Fatalf("%#v != %#v", c.wants, recv)
+		}
+	}
+}
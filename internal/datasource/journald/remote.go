@@ -0,0 +1,59 @@
+package journald
+
+import (
+	"io"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/journal"
+)
+
+// Receives journal entries pushed in the Journal Export Format (e.g. by `systemd-journal-upload`).
+type RemoteWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+// Records are reassembled and decoded same as `journald`, then written to w as JSON lines.
+func NewRemoteWriter(cfg *JournaldConfig, w io.Writer) *RemoteWriter {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+
+	go func() {
+		err := func() error {
+			for raw, err := range iterRecords(cfg, time.Time{}, time.Time{}, pr, func() {}, nil) {
+				if err != nil {
+					return err
+				}
+
+				if _, err := w.Write(append(raw, '\n')); err != nil {
+					return err
+				}
+			}
+			return nil
+		}()
+		// Unblock the writer on error.
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	return &RemoteWriter{
+		pw:   pw,
+		done: done,
+	}
+}
+
+func (w *RemoteWriter) WriteEntry(e *journal.Entry) error {
+	b, err := e.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.pw.Write(append(b, '\n'))
+	return err
+}
+
+// Flush pending partial messages and wait for the records to be written.
+func (w *RemoteWriter) Close() error {
+	_ = w.pw.Close()
+	return <-w.done
+}
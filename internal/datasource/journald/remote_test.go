@@ -0,0 +1,230 @@
+package journald_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/journald"
+	"github.com/ysuzuki-bysystems/seigo/internal/journal"
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+)
+
+func TestRemoteWriter(t *testing.T) {
+	data := `MESSAGE={"data":"loooooooong
+CONTAINER_PARTIAL_MESSAGE=true
+
+MESSAGE=-message"}
+
+MESSAGE=not json
+
+MESSAGE={"n":1}
+`
+
+	buf := new(bytes.Buffer)
+	w := journald.NewRemoteWriter(&journald.JournaldConfig{}, buf)
+	for e, err := range journal.ReadExport(bytes.NewBufferString(data)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wants := `{"data":"loooooooong-message"}
+{"n":1}
+`
+	if buf.String() != wants {
+		t.Fatalf("%q != %q", buf.String(), wants)
+	}
+}
+
+func TestRemoteWriterParser(t *testing.T) {
+	data := `MESSAGE=level=info msg="hello world"
+
+MESSAGE=not logfmt
+
+`
+
+	cfg := &journald.JournaldConfig{
+		Config: parser.Config{Parser: "logfmt", NonJson: "wrap"},
+	}
+	buf := new(bytes.Buffer)
+	w := journald.NewRemoteWriter(cfg, buf)
+	for e, err := range journal.ReadExport(bytes.NewBufferString(data)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wants := `{"level":"info","msg":"hello world"}
+{"message":"not logfmt","_raw":true}
+`
+	if buf.String() != wants {
+		t.Fatalf("%q != %q", buf.String(), wants)
+	}
+}
+
+func TestRemoteWriterFields(t *testing.T) {
+	data := `MESSAGE={"n":1}
+_SYSTEMD_UNIT=app.service
+_HOSTNAME=web1
+PRIORITY=6
+
+MESSAGE=plain text
+_SYSTEMD_UNIT=app.service
+PRIORITY=3
+
+`
+
+	cases := []struct {
+		cfg   *journald.JournaldConfig
+		wants string
+	}{
+		{
+			cfg: &journald.JournaldConfig{
+				Fields: []string{"_SYSTEMD_UNIT", "_HOSTNAME"},
+			},
+			wants: `{"n":1,"_journal":{"_HOSTNAME":"web1","_SYSTEMD_UNIT":"app.service"}}
+`,
+		},
+		{
+			cfg: &journald.JournaldConfig{
+				Fields:      []string{"_SYSTEMD_UNIT", "_HOSTNAME"},
+				MergeFields: true,
+				Config:      parser.Config{NonJson: "fields"},
+			},
+			wants: `{"n":1,"_HOSTNAME":"web1","_SYSTEMD_UNIT":"app.service"}
+{"MESSAGE":"plain text","_SYSTEMD_UNIT":"app.service"}
+`,
+		},
+		{
+			cfg: &journald.JournaldConfig{
+				FieldsKey: "journal",
+				Fields:    []string{"PRIORITY"},
+				Config:    parser.Config{NonJson: "wrap"},
+			},
+			wants: `{"n":1,"journal":{"PRIORITY":"6"}}
+{"message":"plain text","_raw":true,"journal":{"PRIORITY":"3"}}
+`,
+		},
+		{
+			cfg: &journald.JournaldConfig{
+				Config: parser.Config{NonJson: "fields"},
+			},
+			wants: `{"n":1}
+{"MESSAGE":"plain text","PRIORITY":"3","_SYSTEMD_UNIT":"app.service"}
+`,
+		},
+	}
+
+	for _, c := range cases {
+		buf := new(bytes.Buffer)
+		w := journald.NewRemoteWriter(c.cfg, buf)
+		for e, err := range journal.ReadExport(bytes.NewBufferString(data)) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteEntry(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if buf.String() != c.wants {
+			t.Fatalf("%q != %q", buf.String(), c.wants)
+		}
+	}
+}
+
+func TestRemoteWriterMergeFields(t *testing.T) {
+	data := `MESSAGE={"_HOSTNAME":"spoofed","n":1}
+_HOSTNAME=web1
+
+`
+
+	buf := new(bytes.Buffer)
+	w := journald.NewRemoteWriter(&journald.JournaldConfig{
+		Fields:      []string{"_HOSTNAME"},
+		MergeFields: true,
+	}, buf)
+	for e, err := range journal.ReadExport(bytes.NewBufferString(data)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replaced, not duplicated.
+	wants := `{"_HOSTNAME":"web1","n":1}
+`
+	if buf.String() != wants {
+		t.Fatalf("%q != %q", buf.String(), wants)
+	}
+}
+
+func TestRemoteWriterFieldValues(t *testing.T) {
+	data := `MESSAGE=plain text
+TAG=a
+TAG=b
+
+`
+
+	cases := []struct {
+		cfg   *journald.JournaldConfig
+		wants string
+	}{
+		{
+			cfg: &journald.JournaldConfig{
+				Fields: []string{"TAG"},
+				Config: parser.Config{NonJson: "wrap"},
+			},
+			wants: `{"message":"plain text","_raw":true,"_journal":{"TAG":"a"}}
+`,
+		},
+		{
+			cfg: &journald.JournaldConfig{
+				Config: parser.Config{NonJson: "fields"},
+			},
+			wants: `{"MESSAGE":"plain text","TAG":"a"}
+`,
+		},
+	}
+
+	for _, c := range cases {
+		buf := new(bytes.Buffer)
+		w := journald.NewRemoteWriter(c.cfg, buf)
+		for e, err := range journal.ReadExport(bytes.NewBufferString(data)) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteEntry(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// The first one of the duplicated fields, same as the matches.
+		if buf.String() != c.wants {
+			t.Fatalf("%q != %q", buf.String(), c.wants)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package journald
+
+import (
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+// Selection of entries, shared by `journald` and `ssh+journald`.
+// Matches within a `match` table are AND-ed (OR-ed for the same field), and the tables are OR-ed.
+func journalctlArgs(cfg *JournaldConfig, opts *types.CollectOpts) []string {
+	args := []string{
+		"--output=json",
+	}
+	after := validCursor(opts.After)
+	if opts.Tail {
+		args = append(args, "--follow")
+	} else if after == "" {
+		// journalctl does not accept both.
+		args = append(args, fmt.Sprintf("--since=%s", opts.Since.Format(time.RFC3339)))
+	}
+	if after != "" {
+		args = append(args, fmt.Sprintf("--after-cursor=%s", after))
+	}
+	if !opts.Tail && !opts.Until.IsZero() {
+		args = append(args, fmt.Sprintf("--until=%s", opts.Until.Format(time.RFC3339)))
+	}
+
+	for _, unit := range cfg.Unit {
+		args = append(args, fmt.Sprintf("--unit=%s", unit))
+	}
+	for _, unit := range cfg.UserUnit {
+		args = append(args, fmt.Sprintf("--user-unit=%s", unit))
+	}
+	for _, ident := range cfg.Identifier {
+		args = append(args, fmt.Sprintf("--identifier=%s", ident))
+	}
+	if cfg.Priority != "" {
+		args = append(args, fmt.Sprintf("--priority=%s", cfg.Priority))
+	}
+	if cfg.Boot != "" {
+		args = append(args, fmt.Sprintf("--boot=%s", cfg.Boot))
+	}
+
+	for i, m := range cfg.Match {
+		if i > 0 {
+			args = append(args, "+")
+		}
+		for _, key := range slices.Sorted(maps.Keys(m)) {
+			args = append(args, fmt.Sprintf("%s=%s", key, m[key]))
+		}
+	}
+
+	return args
+}
+
+// e.g. `s=...;i=...;b=...;m=...;t=...;x=...`
+var cursorPattern = regexp.MustCompile(`^[a-z]=[0-9a-f]+(;[a-z]=[0-9a-f]+)*$`)
+
+// Cursors come from clients, and are passed to remote shells. Empty if invalid.
+func validCursor(cursor string) string {
+	if !cursorPattern.MatchString(cursor) {
+		return ""
+	}
+	return cursor
+}
+
+// https://www.freedesktop.org/software/systemd/man/latest/journalctl.html#-p
+var priorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+func parsePriorityLevel(text string) (int, error) {
+	if i := slices.Index(priorities, text); i >= 0 {
+		return i, nil
+	}
+	if n, err := strconv.Atoi(text); err == nil && n >= 0 && n < len(priorities) {
+		return n, nil
+	}
+	return 0, fmt.Errorf("invalid priority: %s", text)
+}
+
+// `PRIORITY` values. e.g. `err` -> 0..3, `warning..err` -> 3..4
+func parsePriority(text string) ([]string, error) {
+	from, to := "0", text
+	if a, b, ok := strings.Cut(text, ".."); ok {
+		from, to = a, b
+	}
+
+	lo, err := parsePriorityLevel(from)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := parsePriorityLevel(to)
+	if err != nil {
+		return nil, err
+	}
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+
+	result := make([]string, 0)
+	for i := lo; i <= hi; i++ {
+		result = append(result, strconv.Itoa(i))
+	}
+	return result, nil
+}
+
+// Same as journalctl. e.g. `app` -> `app.service`
+func unitName(name string) string {
+	if strings.Contains(name, ".") {
+		return name
+	}
+	return name + ".service"
+}
+
+// Boot ID for `boot`. Offsets (e.g. `-1`) need the list of boots, and are not supported.
+func bootId(boot string) (string, error) {
+	id := strings.ReplaceAll(boot, "-", "")
+	if b, err := hex.DecodeString(id); err != nil || len(b) != 16 {
+		return "", fmt.Errorf("boot offsets are not supported: %s", boot)
+	}
+	return strings.ToLower(id), nil
+}
+
+// One of the values. Conditions in a group are AND-ed.
+type matchCondition struct {
+	field  string
+	values []string
+}
+
+// Selection as match groups, for sources without journalctl. Groups are OR-ed.
+// Boot offsets are left to resolveBoot.
+func (c *JournaldConfig) matchGroups(resolveBoot func(string) (*matchCondition, error)) ([][]*matchCondition, error) {
+	common := make([]*matchCondition, 0)
+	add := func(field string, values []string) {
+		if len(values) > 0 {
+			common = append(common, &matchCondition{field: field, values: values})
+		}
+	}
+
+	units := make([]string, 0)
+	for _, unit := range c.Unit {
+		units = append(units, unitName(unit))
+	}
+	add("_SYSTEMD_UNIT", units)
+
+	userUnits := make([]string, 0)
+	for _, unit := range c.UserUnit {
+		userUnits = append(userUnits, unitName(unit))
+	}
+	add("_SYSTEMD_USER_UNIT", userUnits)
+
+	add("SYSLOG_IDENTIFIER", c.Identifier)
+
+	if c.Priority != "" {
+		values, err := parsePriority(c.Priority)
+		if err != nil {
+			return nil, err
+		}
+		add("PRIORITY", values)
+	}
+
+	if c.Boot != "" {
+		cond, err := resolveBoot(c.Boot)
+		if err != nil {
+			return nil, err
+		}
+		if cond != nil {
+			common = append(common, cond)
+		}
+	}
+
+	if len(c.Match) == 0 {
+		return [][]*matchCondition{common}, nil
+	}
+
+	groups := make([][]*matchCondition, 0, len(c.Match))
+	for _, m := range c.Match {
+		group := slices.Clone(common)
+		for _, key := range slices.Sorted(maps.Keys(m)) {
+			group = append(group, &matchCondition{field: key, values: []string{m[key]}})
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
@@ -13,10 +13,14 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/ysuzuki-bysystems/seigo/internal/datasource/journald"
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient/sshtest"
 	"github.com/ysuzuki-bysystems/seigo/internal/types"
 	"golang.org/x/crypto/ssh"
 	_ "golang.org/x/crypto/ssh"
@@ -323,11 +327,13 @@ func TestSshJournaldCollect(t *testing.T) {
 	t.Run("auth file", func(t *testing.T) {
 		cfgPath := "."
 		cfg := &journald.SshJournaldConfig{
-			Hostname:           addr.IP.String(),
-			Port:               uint16(port),
-			Username:           "bob",
-			IdentityFile:       ident,
-			UserKnownHostsFile: kh,
+			Config: sshclient.Config{
+				Hostname:           addr.IP.String(),
+				Port:               uint16(port),
+				Username:           "bob",
+				IdentityFile:       ident,
+				UserKnownHostsFile: kh,
+			},
 		}
 		opts := &types.CollectOpts{
 			Since: time.Time{},
@@ -344,7 +350,7 @@ func TestSshJournaldCollect(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			wants := `{"data":"\"journalctl\" \"--output=json\" \"--since=0001-01-01T00:00:00Z\""}`
+			wants := `{"data":"'journalctl' '--output=json' '--since=0001-01-01T00:00:00Z'"}`
 			if string(item) != wants {
 				t.Fatalf("%s != %s", item, wants)
 			}
@@ -373,11 +379,13 @@ func TestSshJournaldCollect(t *testing.T) {
 
 		cfgPath := "."
 		cfg := &journald.SshJournaldConfig{
-			Hostname:           addr.IP.String(),
-			Port:               uint16(port),
-			Username:           "bob",
-			IdentityAgent:      agentPath,
-			UserKnownHostsFile: kh,
+			Config: sshclient.Config{
+				Hostname:           addr.IP.String(),
+				Port:               uint16(port),
+				Username:           "bob",
+				IdentityAgent:      agentPath,
+				UserKnownHostsFile: kh,
+			},
 		}
 		opts := &types.CollectOpts{
 			Since: time.Time{},
@@ -394,7 +402,7 @@ func TestSshJournaldCollect(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			wants := `{"data":"\"journalctl\" \"--output=json\" \"--since=0001-01-01T00:00:00Z\""}`
+			wants := `{"data":"'journalctl' '--output=json' '--since=0001-01-01T00:00:00Z'"}`
 			if string(item) != wants {
 				t.Fatalf("%s != %s", item, wants)
 			}
@@ -428,12 +436,14 @@ func TestSshJournaldCollect(t *testing.T) {
 
 		cfgPath := "."
 		cfg := &journald.SshJournaldConfig{
-			Hostname:           addr.IP.String(),
-			Port:               uint16(port),
-			Username:           "bob",
-			IdentityAgent:      agentPath,
-			IdentityFile:       ident,
-			UserKnownHostsFile: kh,
+			Config: sshclient.Config{
+				Hostname:           addr.IP.String(),
+				Port:               uint16(port),
+				Username:           "bob",
+				IdentityAgent:      agentPath,
+				IdentityFile:       ident,
+				UserKnownHostsFile: kh,
+			},
 		}
 		opts := &types.CollectOpts{
 			Since: time.Time{},
@@ -450,7 +460,7 @@ func TestSshJournaldCollect(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			wants := `{"data":"\"journalctl\" \"--output=json\" \"--since=0001-01-01T00:00:00Z\""}`
+			wants := `{"data":"'journalctl' '--output=json' '--since=0001-01-01T00:00:00Z'"}`
 			if string(item) != wants {
 				t.Fatalf("%s != %s", item, wants)
 			}
@@ -461,3 +471,51 @@ func TestSshJournaldCollect(t *testing.T) {
 		}
 	})
 }
+
+func TestSshJournaldCollectQuote(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	server := sshtest.NewServer(t)
+
+	// Prints each argument as MESSAGE.
+	stub := filepath.Join(t.TempDir(), "journalctl")
+	script := "#!/bin/sh\nfor a in \"$@\"; do printf '{\"MESSAGE\":\"%s\"}\\n' \"$a\"; done\n"
+	if err := os.WriteFile(stub, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &journald.SshJournaldConfig{
+		JournaldConfig: journald.JournaldConfig{
+			JournalctlCmd: stub,
+			Unit:          []string{"a$(echo injected)`echo b`'c"},
+			Config: parser.Config{
+				NonJson: "wrap",
+			},
+		},
+		Config: server.Config,
+	}
+	opts := &types.CollectOpts{
+		Since: time.Unix(0, 0).UTC(),
+	}
+
+	iter, err := journald.SshJournaldCollect(t.Context(), ".", cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for item, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+		recv = append(recv, string(item))
+	}
+
+	wants := []string{
+		`{"message":"--output=json","_raw":true}`,
+		`{"message":"--since=1970-01-01T00:00:00Z","_raw":true}`,
+		`{"message":"--unit=a$(echo injected)` + "`echo b`" + `'c","_raw":true}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
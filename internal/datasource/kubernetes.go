@@ -0,0 +1,27 @@
+//go:build !no_kubernetes
+
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/kubernetes"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type kubernetesDatasource struct{}
+
+func (d *kubernetesDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	var cfg kubernetes.KubernetesConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return kubernetes.KubernetesCollect(cx, env.path, &cfg, opts)
+}
+
+func init() {
+	registerDatasource("kubernetes", new(kubernetesDatasource))
+}
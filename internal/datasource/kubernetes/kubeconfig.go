@@ -0,0 +1,213 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// https://kubernetes.io/docs/reference/config-api/kubeconfig.v1/
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			TlsServerName            string `yaml:"tls-server-name"`
+			InsecureSkipTlsVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			Username              string `yaml:"username"`
+			Password              string `yaml:"password"`
+			Exec                  any    `yaml:"exec"`
+			AuthProvider          any    `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+func defaultKubeconfigPath() (string, error) {
+	if val, ok := os.LookupEnv("KUBECONFIG"); ok && val != "" {
+		// Merging multiple files is not supported. Use the first one.
+		return filepath.SplitList(val)[0], nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// Inline data or file relative to the kubeconfig.
+func readData(dir, data, file string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+
+	if file == "" {
+		return nil, nil
+	}
+
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	return os.ReadFile(file)
+}
+
+type restConfig struct {
+	server    string
+	namespace string
+	client    *http.Client
+	authorize func(*http.Request)
+}
+
+func loadKubeconfig(path, contextName string) (*restConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var kc kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+
+	if contextName == "" {
+		contextName = kc.CurrentContext
+	}
+	if contextName == "" {
+		return nil, errors.New("no context")
+	}
+
+	ctxIdx := -1
+	for i, c := range kc.Contexts {
+		if c.Name == contextName {
+			ctxIdx = i
+			break
+		}
+	}
+	if ctxIdx < 0 {
+		return nil, fmt.Errorf("context not found: %s", contextName)
+	}
+	ctx := kc.Contexts[ctxIdx].Context
+
+	result := &restConfig{
+		namespace: ctx.Namespace,
+		authorize: func(*http.Request) {},
+	}
+	tlsConfig := &tls.Config{}
+
+	found := false
+	for _, c := range kc.Clusters {
+		if c.Name != ctx.Cluster {
+			continue
+		}
+
+		found = true
+		result.server = strings.TrimSuffix(c.Cluster.Server, "/")
+		tlsConfig.ServerName = c.Cluster.TlsServerName
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTlsVerify
+
+		ca, err := readData(dir, c.Cluster.CertificateAuthorityData, c.Cluster.CertificateAuthority)
+		if err != nil {
+			return nil, err
+		}
+		if ca != nil {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, errors.New("invalid certificate-authority")
+			}
+			tlsConfig.RootCAs = pool
+		}
+		break
+	}
+	if !found {
+		return nil, fmt.Errorf("cluster not found: %s", ctx.Cluster)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name != ctx.User {
+			continue
+		}
+
+		if u.User.Exec != nil || u.User.AuthProvider != nil {
+			return nil, errors.New("exec / auth-provider credentials are not supported")
+		}
+
+		cert, err := readData(dir, u.User.ClientCertificateData, u.User.ClientCertificate)
+		if err != nil {
+			return nil, err
+		}
+		key, err := readData(dir, u.User.ClientKeyData, u.User.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		if cert != nil && key != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+
+		token := u.User.Token
+		if token == "" && u.User.TokenFile != "" {
+			b, err := readData(dir, "", u.User.TokenFile)
+			if err != nil {
+				return nil, err
+			}
+			token = strings.TrimSpace(string(b))
+		}
+
+		switch {
+		case token != "":
+			result.authorize = func(req *http.Request) {
+				req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			}
+		case u.User.Username != "":
+			username, password := u.User.Username, u.User.Password
+			result.authorize = func(req *http.Request) {
+				req.SetBasicAuth(username, password)
+			}
+		}
+		break
+	}
+
+	result.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+	}
+
+	return result, nil
+}
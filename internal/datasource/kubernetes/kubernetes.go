@@ -0,0 +1,258 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/merge"
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+	"github.com/ysuzuki-bysystems/seigo/internal/record"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+func resolvePath(cfgPath, target string) string {
+	if filepath.IsAbs(target) {
+		return target
+	}
+
+	dir := filepath.Dir(cfgPath)
+	return filepath.Join(dir, target)
+}
+
+type KubernetesConfig struct {
+	parser.Config
+	// Defaults to `$KUBECONFIG` or `~/.kube/config`.
+	Kubeconfig string `json:"kubeconfig"`
+	// Defaults to `current-context`.
+	Context string `json:"context"`
+	// Defaults to the namespace of the context, or `default`.
+	Namespace string `json:"namespace"`
+	// Label selector. e.g. `app=web,tier!=cache`
+	Selector string `json:"selector"`
+	// Empty means all containers of the pods.
+	Containers []string `json:"containers"`
+}
+
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Containers []struct {
+				Name string `json:"name"`
+			} `json:"containers"`
+		} `json:"spec"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type target struct {
+	pod       string
+	container string
+}
+
+func (r *restConfig) get(cx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := r.server + path
+	if len(query) > 0 {
+		u = fmt.Sprintf("%s?%s", u, query.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(cx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.authorize(req)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		// https://kubernetes.io/docs/reference/kubernetes-api/common-definitions/status/
+		var status struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&status)
+		return nil, fmt.Errorf("GET %s: %s %s", path, resp.Status, status.Message)
+	}
+
+	return resp, nil
+}
+
+func listTargets(cx context.Context, rc *restConfig, namespace string, cfg *KubernetesConfig, runningOnly bool) ([]target, error) {
+	query := url.Values{}
+	if cfg.Selector != "" {
+		query.Set("labelSelector", cfg.Selector)
+	}
+
+	resp, err := rc.get(cx, fmt.Sprintf("/api/v1/namespaces/%s/pods", url.PathEscape(namespace)), query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var pods podList
+	if err := json.NewDecoder(resp.Body).Decode(&pods); err != nil {
+		return nil, err
+	}
+
+	result := make([]target, 0)
+	for _, pod := range pods.Items {
+		if runningOnly && pod.Status.Phase != "Running" {
+			continue
+		}
+
+		for _, c := range pod.Spec.Containers {
+			if len(cfg.Containers) > 0 && !slices.Contains(cfg.Containers, c.Name) {
+				continue
+			}
+
+			result = append(result, target{pod: pod.Metadata.Name, container: c.Name})
+		}
+	}
+
+	return result, nil
+}
+
+// Lines are prefixed with their timestamps to order the containers, and to stop at until as no parameter for the end.
+// In tail mode, lines since the time are followed. Zero means only new lines.
+func streamLogs(cx context.Context, rc *restConfig, conv *parser.Converter, namespace string, t target, opts *types.CollectOpts, since time.Time, yield func(*merge.Entry, error) bool) bool {
+	query := url.Values{}
+	query.Set("container", t.container)
+	query.Set("timestamps", "true")
+	until := opts.Until
+	if opts.Tail {
+		query.Set("follow", "true")
+		if since.IsZero() {
+			query.Set("tailLines", "0")
+		} else {
+			query.Set("sinceTime", since.UTC().Format(time.RFC3339))
+		}
+		until = time.Time{}
+	} else {
+		query.Set("sinceTime", opts.Since.UTC().Format(time.RFC3339))
+	}
+
+	path := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/log", url.PathEscape(namespace), url.PathEscape(t.pod))
+	resp, err := rc.get(cx, path, query)
+	if err != nil {
+		return yield(nil, err)
+	}
+	defer resp.Body.Close()
+
+	tags := map[string]any{
+		"_pod":       t.pod,
+		"_container": t.container,
+	}
+
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimRight(line, "\r\n")
+			// `<RFC 3339> <line>`
+			var ts time.Time
+			prefix, rest, _ := bytes.Cut(line, []byte(" "))
+			if parsed, err := time.Parse(time.RFC3339Nano, string(prefix)); err == nil {
+				if !until.IsZero() && parsed.After(until) {
+					return true
+				}
+				ts, line = parsed, rest
+			}
+
+			raw, err := conv.Convert(line)
+			if err != nil {
+				return yield(nil, err)
+			}
+
+			if raw != nil {
+				raw, err = record.Merge(raw, tags)
+				if err != nil {
+					return yield(nil, err)
+				}
+
+				if !yield(&merge.Entry{Raw: raw, Time: ts}, nil) {
+					return false
+				}
+			}
+			// else drop & skip
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) || cx.Err() != nil {
+				return true
+			}
+			return yield(nil, err)
+		}
+	}
+}
+
+func KubernetesCollect(cx context.Context, cfgPath string, cfg *KubernetesConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	path := cfg.Kubeconfig
+	if path == "" {
+		var err error
+		path, err = defaultKubeconfigPath()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		path = resolvePath(cfgPath, path)
+	}
+
+	conv, err := parser.NewConverter(&cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := loadKubeconfig(path, cfg.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = rc.namespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	// Running ones are listed again in tail mode.
+	list := func(cx context.Context) ([]merge.Stream, error) {
+		targets, err := listTargets(cx, rc, namespace, cfg, opts.Tail)
+		if err != nil {
+			return nil, err
+		}
+
+		streams := make([]merge.Stream, 0, len(targets))
+		for _, t := range targets {
+			streams = append(streams, merge.Stream{
+				Key: t.pod + "/" + t.container,
+				Open: func(cx context.Context, since time.Time) iter.Seq2[*merge.Entry, error] {
+					return func(yield func(*merge.Entry, error) bool) {
+						streamLogs(cx, rc, conv, namespace, t, opts, since, yield)
+					}
+				},
+			})
+		}
+		return streams, nil
+	}
+	return merge.Streams(cx, opts.Tail, list)
+}
@@ -0,0 +1,171 @@
+package kubernetes_test
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/kubernetes"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+func newApiServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/namespaces/staging/pods", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("labelSelector") != "app=web" {
+			http.Error(w, r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"items":[
+			{"metadata":{"name":"web-1"},"spec":{"containers":[{"name":"app"},{"name":"sidecar"}]},"status":{"phase":"Running"}},
+			{"metadata":{"name":"web-2"},"spec":{"containers":[{"name":"app"}]},"status":{"phase":"Succeeded"}}
+		]}`))
+	})
+	mux.HandleFunc("GET /api/v1/namespaces/staging/pods/{pod}/log", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sinceTime") != "2024-01-01T00:00:00Z" || r.URL.Query().Get("timestamps") != "true" {
+			http.Error(w, r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+
+		// Sidecars log earlier.
+		container := r.URL.Query().Get("container")
+		ts := "2024-01-01T00:00:01.5Z"
+		if container == "sidecar" {
+			ts = "2024-01-01T00:00:01.2Z"
+		}
+		_, _ = fmt.Fprintf(w, "%s {\"msg\":\"hello from %s\"}\n2024-01-01T00:00:03Z plain text\n", ts, container)
+	})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Returns the path of the config file next to the kubeconfig.
+func writeKubeconfig(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: staging
+clusters:
+- name: staging
+  cluster:
+    server: %s
+    certificate-authority-data: %s
+contexts:
+- name: staging
+  context:
+    cluster: staging
+    user: staging
+    namespace: staging
+users:
+- name: staging
+  user:
+    tokenFile: ./token
+`, server.URL, base64.StdEncoding.EncodeToString(ca))
+
+	tmpdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpdir, "kubeconfig"), []byte(kubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpdir, "token"), []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(tmpdir, "config.toml")
+}
+
+func collectAll(t *testing.T, cfg *kubernetes.KubernetesConfig, opts *types.CollectOpts) []string {
+	t.Helper()
+
+	server := newApiServer(t)
+	cfgPath := writeKubeconfig(t, server)
+
+	opts.Since = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	iter, err := kubernetes.KubernetesCollect(t.Context(), cfgPath, cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv = append(recv, string(ent))
+	}
+	return recv
+}
+
+func TestKubernetesCollect(t *testing.T) {
+	cfg := &kubernetes.KubernetesConfig{
+		Kubeconfig: "./kubeconfig",
+		Selector:   "app=web",
+	}
+	recv := collectAll(t, cfg, &types.CollectOpts{})
+
+	// By the timestamps of the lines.
+	wants := []string{
+		`{"msg":"hello from sidecar","_container":"sidecar","_pod":"web-1"}`,
+		`{"msg":"hello from app","_container":"app","_pod":"web-1"}`,
+		`{"msg":"hello from app","_container":"app","_pod":"web-2"}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
+
+func TestKubernetesCollectNonJson(t *testing.T) {
+	cfg := &kubernetes.KubernetesConfig{
+		Kubeconfig: "./kubeconfig",
+		Selector:   "app=web",
+		Containers: []string{"sidecar"},
+	}
+	cfg.NonJson = "wrap"
+	recv := collectAll(t, cfg, &types.CollectOpts{})
+
+	wants := []string{
+		`{"msg":"hello from sidecar","_container":"sidecar","_pod":"web-1"}`,
+		`{"message":"plain text","_raw":true,"_container":"sidecar","_pod":"web-1"}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
+
+func TestKubernetesCollectUntil(t *testing.T) {
+	cfg := &kubernetes.KubernetesConfig{
+		Kubeconfig: "./kubeconfig",
+		Selector:   "app=web",
+		Containers: []string{"sidecar"},
+	}
+	cfg.NonJson = "wrap"
+	recv := collectAll(t, cfg, &types.CollectOpts{
+		Until: time.Date(2024, 1, 1, 0, 0, 2, 0, time.UTC),
+	})
+
+	wants := []string{
+		`{"msg":"hello from sidecar","_container":"sidecar","_pod":"web-1"}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !no_loki
+
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/loki"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type lokiDatasource struct{}
+
+func (d *lokiDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	var cfg loki.LokiConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return loki.LokiCollect(cx, &cfg, opts)
+}
+
+func init() {
+	registerDatasource("loki", new(lokiDatasource))
+}
@@ -0,0 +1,325 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/record"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+	"golang.org/x/net/websocket"
+)
+
+var defaultLimit = 1000
+
+type LokiConfig struct {
+	// e.g. `http://localhost:3100`
+	Url string `json:"url"`
+	// LogQL. e.g. `{app="web"}`
+	Query string `json:"query"`
+	// `X-Scope-OrgID`
+	TenantId    string `json:"tenant-id"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	BearerToken string `json:"bearer-token"`
+	// Entries per request.
+	Limit int `json:"limit"`
+}
+
+func (c *LokiConfig) header() http.Header {
+	h := http.Header{}
+	if c.TenantId != "" {
+		h.Set("X-Scope-OrgID", c.TenantId)
+	}
+	switch {
+	case c.BearerToken != "":
+		h.Set("Authorization", fmt.Sprintf("Bearer %s", c.BearerToken))
+	case c.Username != "":
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(c.Username, c.Password)
+		h.Set("Authorization", req.Header.Get("Authorization"))
+	}
+	return h
+}
+
+func (c *LokiConfig) limit() int {
+	if c.Limit <= 0 {
+		return defaultLimit
+	}
+	return c.Limit
+}
+
+// https://grafana.com/docs/loki/latest/reference/loki-http-api/#query-logs-within-a-range-of-time
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	// [["<unix epoch in nanoseconds>", "<log line>"], ...]
+	Values [][2]string `json:"values"`
+}
+
+type queryRangeResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string   `json:"resultType"`
+		Result     []stream `json:"result"`
+	} `json:"data"`
+}
+
+type entry struct {
+	ts     int64
+	line   string
+	labels map[string]string
+}
+
+func (e *entry) key() string {
+	var b strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(e.labels)) {
+		fmt.Fprintf(&b, "%s=%q,", k, e.labels[k])
+	}
+	b.WriteString(e.line)
+	return b.String()
+}
+
+func flatten(streams []stream) ([]*entry, error) {
+	entries := make([]*entry, 0)
+	for _, s := range streams {
+		for _, v := range s.Values {
+			ts, err := strconv.ParseInt(v[0], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, &entry{ts: ts, line: v[1], labels: s.Stream})
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a, b *entry) int {
+		switch {
+		case a.ts < b.ts:
+			return -1
+		case a.ts > b.ts:
+			return 1
+		}
+		return 0
+	})
+
+	return entries, nil
+}
+
+func toRecord(e *entry) (json.RawMessage, error) {
+	tags := map[string]any{
+		"_labels": e.labels,
+	}
+
+	var raw json.RawMessage
+	if err := json.Unmarshal([]byte(e.line), &raw); err == nil && strings.HasPrefix(strings.TrimSpace(e.line), "{") {
+		return record.Merge(raw, tags)
+	}
+
+	tags["message"] = e.line
+	return record.Merge(json.RawMessage("{}"), tags)
+}
+
+func queryRange(cx context.Context, cfg *LokiConfig, start, end int64) ([]*entry, error) {
+	query := url.Values{}
+	query.Set("query", cfg.Query)
+	query.Set("start", strconv.FormatInt(start, 10))
+	query.Set("end", strconv.FormatInt(end, 10))
+	query.Set("limit", strconv.Itoa(cfg.limit()))
+	query.Set("direction", "forward")
+
+	u := fmt.Sprintf("%s/loki/api/v1/query_range?%s", strings.TrimSuffix(cfg.Url, "/"), query.Encode())
+	req, err := http.NewRequestWithContext(cx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = cfg.header()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("query_range: %s", resp.Status)
+	}
+
+	var body queryRangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("query_range: unexpected result type %q. Use a log query.", body.Data.ResultType)
+	}
+
+	return flatten(body.Data.Result)
+}
+
+// until is inclusive. Zero for now.
+func iterHistory(cx context.Context, cfg *LokiConfig, since, until time.Time) iter.Seq2[json.RawMessage, error] {
+	return func(yield func(json.RawMessage, error) bool) {
+		start := since.UnixNano()
+		end := time.Now().UnixNano()
+		if !until.IsZero() {
+			// `end` is exclusive.
+			end = until.UnixNano() + 1
+		}
+		// Entries at `start` which are already emitted.
+		seen := make(map[string]struct{})
+
+		for {
+			entries, err := queryRange(cx, cfg, start, end)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			emitted := 0
+			nextSeen := make(map[string]struct{})
+			last := start
+			for _, e := range entries {
+				if e.ts == start {
+					if _, ok := seen[e.key()]; ok {
+						continue
+					}
+				}
+
+				raw, err := toRecord(e)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				if !yield(raw, nil) {
+					return
+				}
+				emitted++
+
+				if e.ts != last {
+					last = e.ts
+					nextSeen = make(map[string]struct{})
+				}
+				nextSeen[e.key()] = struct{}{}
+			}
+
+			if len(entries) < cfg.limit() {
+				return
+			}
+
+			if emitted == 0 {
+				// More than `limit` entries in the same nanosecond. Give up the rest.
+				start++
+				seen = make(map[string]struct{})
+				continue
+			}
+
+			if last == start {
+				for k := range nextSeen {
+					seen[k] = struct{}{}
+				}
+			} else {
+				start = last
+				seen = nextSeen
+			}
+		}
+	}
+}
+
+// https://grafana.com/docs/loki/latest/reference/loki-http-api/#stream-logs
+type tailResponse struct {
+	Streams []stream `json:"streams"`
+}
+
+func iterTail(cx context.Context, cfg *LokiConfig) (iter.Seq2[json.RawMessage, error], error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.Url, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	origin := base.String()
+	switch base.Scheme {
+	case "http":
+		base.Scheme = "ws"
+	case "https":
+		base.Scheme = "wss"
+	default:
+		return nil, fmt.Errorf("Unsupported url: %s", cfg.Url)
+	}
+
+	query := url.Values{}
+	query.Set("query", cfg.Query)
+	query.Set("start", strconv.FormatInt(time.Now().UnixNano(), 10))
+	query.Set("limit", strconv.Itoa(cfg.limit()))
+	base.Path = fmt.Sprintf("%s/loki/api/v1/tail", base.Path)
+	base.RawQuery = query.Encode()
+
+	wsConfig, err := websocket.NewConfig(base.String(), origin)
+	if err != nil {
+		return nil, err
+	}
+	wsConfig.Header = cfg.header()
+
+	conn, err := wsConfig.DialContext(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(json.RawMessage, error) bool) {
+		defer conn.Close()
+
+		stop := context.AfterFunc(cx, func() {
+			_ = conn.Close()
+		})
+		defer stop()
+
+		for {
+			var msg tailResponse
+			if err := websocket.JSON.Receive(conn, &msg); err != nil {
+				if cx.Err() != nil {
+					return
+				}
+				yield(nil, err)
+				return
+			}
+
+			entries, err := flatten(msg.Streams)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, e := range entries {
+				raw, err := toRecord(e)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				if !yield(raw, nil) {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+func LokiCollect(cx context.Context, cfg *LokiConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	if cfg.Url == "" {
+		return nil, errors.New("empty url")
+	}
+	if cfg.Query == "" {
+		return nil, errors.New("empty query")
+	}
+
+	if opts.Tail {
+		return iterTail(cx, cfg)
+	}
+
+	return iterHistory(cx, cfg, opts.Since, opts.Until), nil
+}
@@ -0,0 +1,176 @@
+package loki_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/loki"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+	"golang.org/x/net/websocket"
+)
+
+type value struct {
+	ts   int64
+	line string
+}
+
+func newLoki(t *testing.T) *httptest.Server {
+	values := []value{
+		{ts: 100, line: `{"n":1}`},
+		{ts: 200, line: `{"n":2}`},
+		{ts: 200, line: `plain`},
+		{ts: 300, line: `{"n":3}`},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /loki/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("query") != `{app="web"}` || q.Get("direction") != "forward" || r.Header.Get("X-Scope-OrgID") != "tenant" {
+			http.Error(w, r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		start, _ := strconv.ParseInt(q.Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(q.Get("end"), 10, 64)
+		limit, _ := strconv.Atoi(q.Get("limit"))
+
+		result := make([][2]string, 0)
+		for _, v := range values {
+			if v.ts < start || v.ts >= end || len(result) >= limit {
+				continue
+			}
+			result = append(result, [2]string{strconv.FormatInt(v.ts, 10), v.line})
+		}
+
+		body := map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"resultType": "streams",
+				"result": []any{
+					map[string]any{
+						"stream": map[string]string{"app": "web"},
+						"values": result,
+					},
+				},
+			},
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	})
+	mux.Handle("GET /loki/api/v1/tail", websocket.Handler(func(conn *websocket.Conn) {
+		defer conn.Close()
+
+		msg := map[string]any{
+			"streams": []any{
+				map[string]any{
+					"stream": map[string]string{"app": "web"},
+					"values": [][2]string{{fmt.Sprint(time.Now().UnixNano()), `{"n":4}`}},
+				},
+			},
+		}
+		if err := websocket.JSON.Send(conn, msg); err != nil {
+			t.Error(err)
+		}
+
+		// Keep open until the client goes away.
+		var discard any
+		_ = websocket.JSON.Receive(conn, &discard)
+	}))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLokiCollect(t *testing.T) {
+	server := newLoki(t)
+
+	cfg := &loki.LokiConfig{
+		Url:      server.URL,
+		Query:    `{app="web"}`,
+		TenantId: "tenant",
+		Limit:    2,
+	}
+
+	t.Run("history", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Since: time.Unix(0, 0),
+		}
+		iter, err := loki.LokiCollect(t.Context(), cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, string(ent))
+		}
+
+		wants := []string{
+			`{"n":1,"_labels":{"app":"web"}}`,
+			`{"n":2,"_labels":{"app":"web"}}`,
+			`{"_labels":{"app":"web"},"message":"plain"}`,
+			`{"n":3,"_labels":{"app":"web"}}`,
+		}
+		if !slices.Equal(wants, recv) {
+			t.Fatalf("%#v != %#v", wants, recv)
+		}
+	})
+
+	t.Run("until", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Since: time.Unix(0, 0),
+			Until: time.Unix(0, 200),
+		}
+		iter, err := loki.LokiCollect(t.Context(), cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, string(ent))
+		}
+
+		wants := []string{
+			`{"n":1,"_labels":{"app":"web"}}`,
+			`{"n":2,"_labels":{"app":"web"}}`,
+			`{"_labels":{"app":"web"},"message":"plain"}`,
+		}
+		if !slices.Equal(wants, recv) {
+			t.Fatalf("%#v != %#v", wants, recv)
+		}
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		opts := &types.CollectOpts{
+			Tail: true,
+		}
+		iter, err := loki.LokiCollect(t.Context(), cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for ent, err := range iter {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wants := `{"n":4,"_labels":{"app":"web"}}`
+			if string(ent) != wants {
+				t.Fatalf("%s != %s", ent, wants)
+			}
+			break
+		}
+	})
+}
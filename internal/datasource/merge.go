@@ -0,0 +1,40 @@
+//go:build !no_merge
+
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"slices"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/merge"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+// Names of the merge collections being collected. Against circular references.
+var contextMergeChainKey = &struct{}{}
+
+type mergeDatasource struct{}
+
+func (d *mergeDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	var cfg merge.MergeConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	chain, _ := cx.Value(contextMergeChainKey).([]string)
+	if slices.Contains(chain, env.name) {
+		return nil, fmt.Errorf("circular merge: %s", env.name)
+	}
+	cx = context.WithValue(cx, contextMergeChainKey, append(slices.Clip(chain), env.name))
+
+	return merge.MergeCollect(cx, &cfg, opts, func(cx context.Context, name string, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+		return Collect(cx, env.config, name, opts)
+	})
+}
+
+func init() {
+	registerDatasource("merge", new(mergeDatasource))
+}
@@ -0,0 +1,3 @@
+package merge
+
+var ListInterval = &listInterval
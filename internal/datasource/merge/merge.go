@@ -0,0 +1,457 @@
+package merge
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/record"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+var defaultReorderWindow = time.Second
+
+// Upper bound of records held for reordering in tail mode.
+var maxPending = 10000
+
+// How often Streams lists the streams again in tail mode.
+var listInterval = 5 * time.Second
+
+type MergeConfig struct {
+	// Names of the collections to merge.
+	Collections    []string `json:"collections"`
+	TimestampField string   `json:"timestamp-field"`
+	// How long records are held to be reordered in tail mode. e.g. `500ms`
+	ReorderWindow string `json:"reorder-window"`
+	// Default: `_collection`
+	CollectionField string `json:"collection-field"`
+}
+
+func (c *MergeConfig) timestampFields() []string {
+	if c.TimestampField == "" {
+		return nil
+	}
+
+	return []string{c.TimestampField}
+}
+
+// Collect the named collection.
+type CollectFunc func(cx context.Context, name string, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error)
+
+type item struct {
+	raw json.RawMessage
+	t   time.Time
+	src int
+	seq uint64
+
+	// Tail mode only.
+	deadline time.Time
+}
+
+func (a *item) less(b *item) bool {
+	if c := a.t.Compare(b.t); c != 0 {
+		return c < 0
+	}
+	if a.src != b.src {
+		return a.src < b.src
+	}
+	return a.seq < b.seq
+}
+
+type itemHeap []*item
+
+func (h itemHeap) Len() int           { return len(h) }
+func (h itemHeap) Less(i, j int) bool { return h[i].less(h[j]) }
+func (h itemHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *itemHeap) Push(x any) {
+	*h = append(*h, x.(*item))
+}
+
+func (h *itemHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return x
+}
+
+type event struct {
+	item *item
+	err  error
+	// The source of src ended.
+	done bool
+	src  int
+}
+
+// Record with the time given by its stream, e.g. prefixed by the container runtime.
+// Zero Time means the timestamp of the record.
+type Entry struct {
+	Raw  json.RawMessage
+	Time time.Time
+}
+
+func entries(seq iter.Seq2[json.RawMessage, error]) iter.Seq2[*Entry, error] {
+	return func(yield func(*Entry, error) bool) {
+		for raw, err := range seq {
+			if !yield(&Entry{Raw: raw}, err) {
+				return
+			}
+		}
+	}
+}
+
+type source struct {
+	// Prefixed to errors if not empty.
+	name string
+	seq  iter.Seq2[*Entry, error]
+	// Merged into each record.
+	tags map[string]any
+	// Records without timestamp follow the preceding record.
+	last time.Time
+	n    uint64
+}
+
+func (s *source) item(timestampFields []string, src int, e *Entry, now func() time.Time) (*item, error) {
+	if !e.Time.IsZero() {
+		s.last = e.Time
+	} else if t, ok := record.Timestamp(e.Raw, timestampFields...); ok {
+		s.last = t
+	} else if s.last.IsZero() && now != nil {
+		s.last = now()
+	}
+
+	tagged, err := record.Merge(e.Raw, s.tags)
+	if err != nil {
+		return nil, err
+	}
+
+	s.n++
+	return &item{raw: tagged, t: s.last, src: src, seq: s.n}, nil
+}
+
+// Send records of the source until cx is done.
+func (s *source) run(cx context.Context, timestampFields []string, src int, now func() time.Time, ch chan<- *event) {
+	send := func(ev *event) bool {
+		select {
+		case ch <- ev:
+			return true
+		case <-cx.Done():
+			return false
+		}
+	}
+
+	for e, err := range s.seq {
+		if err != nil {
+			if s.name != "" {
+				err = fmt.Errorf("%s: %w", s.name, err)
+			}
+			send(&event{err: err})
+			return
+		}
+
+		it, err := s.item(timestampFields, src, e, now)
+		if err != nil {
+			send(&event{err: err})
+			return
+		}
+
+		if !send(&event{item: it}) {
+			return
+		}
+	}
+
+	send(&event{done: true, src: src})
+}
+
+// Ordered by timestamp. Each source is expected to be ordered.
+func iterHistory(cx context.Context, cancel context.CancelFunc, timestampFields []string, sources []*source) iter.Seq2[json.RawMessage, error] {
+	return func(yield func(json.RawMessage, error) bool) {
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		defer cancel()
+
+		chs := make([]chan *event, len(sources))
+		for i, s := range sources {
+			chs[i] = make(chan *event, 64)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.run(cx, timestampFields, i, nil, chs[i])
+			}()
+		}
+
+		h := make(itemHeap, 0, len(sources))
+		// Wait for the next record of src.
+		next := func(src int) bool {
+			select {
+			case ev := <-chs[src]:
+				if ev.err != nil {
+					yield(nil, ev.err)
+					return false
+				}
+				if ev.item != nil {
+					heap.Push(&h, ev.item)
+				}
+				return true
+
+			case <-cx.Done():
+				return false
+			}
+		}
+
+		for src := range sources {
+			if !next(src) {
+				return
+			}
+		}
+
+		for h.Len() > 0 {
+			it := heap.Pop(&h).(*item)
+			if !yield(it.raw, nil) {
+				return
+			}
+
+			if !next(it.src) {
+				return
+			}
+		}
+	}
+}
+
+// Records are held for the window to be ordered with late records of other sources.
+func iterTail(cx context.Context, cancel context.CancelFunc, timestampFields []string, sources []*source, window time.Duration) iter.Seq2[json.RawMessage, error] {
+	return func(yield func(json.RawMessage, error) bool) {
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		defer cancel()
+
+		ch := make(chan *event, 64)
+		for i, s := range sources {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.run(cx, timestampFields, i, time.Now, ch)
+			}()
+		}
+
+		timer := time.NewTimer(window)
+		timer.Stop()
+		defer timer.Stop()
+
+		h := make(itemHeap, 0)
+		remaining := len(sources)
+
+		flush := func(all bool) bool {
+			now := time.Now()
+			for h.Len() > 0 && (all || h.Len() > maxPending || !h[0].deadline.After(now)) {
+				it := heap.Pop(&h).(*item)
+				if !yield(it.raw, nil) {
+					return false
+				}
+			}
+
+			if h.Len() > 0 {
+				timer.Reset(time.Until(h[0].deadline))
+			}
+			return true
+		}
+
+		for remaining > 0 {
+			select {
+			case <-cx.Done():
+				return
+
+			case ev := <-ch:
+				switch {
+				case ev.err != nil:
+					if !flush(true) {
+						return
+					}
+					yield(nil, ev.err)
+					return
+
+				case ev.done:
+					remaining--
+
+				default:
+					ev.item.deadline = time.Now().Add(window)
+					heap.Push(&h, ev.item)
+				}
+
+				timer.Stop()
+				if !flush(remaining == 0) {
+					return
+				}
+
+			case <-timer.C:
+				if !flush(false) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func MergeCollect(cx context.Context, cfg *MergeConfig, opts *types.CollectOpts, collect CollectFunc) (iter.Seq2[json.RawMessage, error], error) {
+	if len(cfg.Collections) == 0 {
+		return nil, errors.New("empty collections")
+	}
+	if cfg.CollectionField == "" {
+		cfg.CollectionField = "_collection"
+	}
+
+	window := defaultReorderWindow
+	if cfg.ReorderWindow != "" {
+		d, err := time.ParseDuration(cfg.ReorderWindow)
+		if err != nil {
+			return nil, err
+		}
+		window = d
+	}
+
+	// Cursors of the sources cannot be combined into one. Merged records have no cursor.
+	sourceOpts := *opts
+	sourceOpts.After = ""
+	sourceOpts.OnCursor = nil
+
+	// Sources are stopped when the merged iteration ends.
+	cx, cancel := context.WithCancel(cx)
+
+	sources := make([]*source, 0, len(cfg.Collections))
+	for _, name := range cfg.Collections {
+		seq, err := collect(cx, name, &sourceOpts)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		sources = append(sources, &source{name: name, seq: entries(seq), tags: map[string]any{cfg.CollectionField: name}})
+	}
+
+	if opts.Tail {
+		return iterTail(cx, cancel, cfg.timestampFields(), sources, window), nil
+	}
+	return iterHistory(cx, cancel, cfg.timestampFields(), sources), nil
+}
+
+// Stream of a datasource reading several streams, e.g. containers.
+type Stream struct {
+	// Identifies the stream across the listings in tail mode. e.g. container id
+	Key string
+	// Started with a context canceled when the iteration ends.
+	// In tail mode, records since the time are followed. Zero means only new records.
+	Open func(cx context.Context, since time.Time) iter.Seq2[*Entry, error]
+}
+
+// Lists the streams to read. e.g. running containers in tail mode.
+type ListFunc func(cx context.Context) ([]Stream, error)
+
+// Records of the listed streams. In history mode, ordered by timestamp as each stream is expected to be ordered.
+// In tail mode, yielded as they arrive. Streams are listed again periodically and new ones are followed.
+func Streams(cx context.Context, tail bool, list ListFunc) (iter.Seq2[json.RawMessage, error], error) {
+	streams, err := list(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(json.RawMessage, error) bool) {
+		cx, cancel := context.WithCancel(cx)
+
+		if tail {
+			tailStreams(cx, cancel, list, streams)(yield)
+			return
+		}
+
+		sources := make([]*source, 0, len(streams))
+		for _, stream := range streams {
+			sources = append(sources, &source{seq: stream.Open(cx, time.Time{})})
+		}
+		iterHistory(cx, cancel, nil, sources)(yield)
+	}, nil
+}
+
+func tailStreams(cx context.Context, cancel context.CancelFunc, list ListFunc, streams []Stream) iter.Seq2[json.RawMessage, error] {
+	return func(yield func(json.RawMessage, error) bool) {
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		defer cancel()
+
+		ch := make(chan *event, 64)
+		started := time.Now()
+		// Keys of the sources, and the running ones.
+		keys := []string{}
+		running := map[string]bool{}
+		// Ended streams are followed again since then, if listed again. e.g. restarted containers
+		ended := map[string]time.Time{}
+
+		// Streams appeared after the start are followed since then.
+		start := func(streams []Stream, since time.Time) {
+			for _, stream := range streams {
+				if running[stream.Key] {
+					continue
+				}
+
+				since := since
+				if t, ok := ended[stream.Key]; ok {
+					since = t
+				}
+
+				s := &source{seq: stream.Open(cx, since)}
+				src := len(keys)
+				keys = append(keys, stream.Key)
+				running[stream.Key] = true
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					s.run(cx, nil, src, nil, ch)
+				}()
+			}
+		}
+		start(streams, time.Time{})
+
+		ticker := time.NewTicker(listInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-cx.Done():
+				return
+
+			case ev := <-ch:
+				switch {
+				case ev.err != nil:
+					yield(nil, ev.err)
+					return
+
+				case ev.done:
+					delete(running, keys[ev.src])
+					ended[keys[ev.src]] = time.Now()
+
+				default:
+					if !yield(ev.item.raw, nil) {
+						return
+					}
+				}
+
+			case <-ticker.C:
+				streams, err := list(cx)
+				if err != nil {
+					if cx.Err() == nil {
+						yield(nil, err)
+					}
+					return
+				}
+				start(streams, started)
+			}
+		}
+	}
+}
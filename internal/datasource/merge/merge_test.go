@@ -0,0 +1,278 @@
+package merge_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/merge"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+func TestMergeCollect(t *testing.T) {
+	sources := map[string][]string{
+		"api": {
+			`{"time":"2024-01-01T00:00:00Z","n":1}`,
+			`{"n":2}`,
+			`{"time":"2024-01-01T00:00:03Z","n":5}`,
+		},
+		"worker": {
+			`{"time":"2024-01-01T00:00:01Z","n":3}`,
+			`{"time":"2024-01-01T00:00:02Z","n":4}`,
+		},
+		"proxy": {},
+	}
+
+	var since time.Time
+	collect := func(cx context.Context, name string, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+		since = opts.Since
+
+		lines, ok := sources[name]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+
+		return func(yield func(json.RawMessage, error) bool) {
+			for _, line := range lines {
+				if !yield(json.RawMessage(line), nil) {
+					return
+				}
+			}
+		}, nil
+	}
+
+	cfg := &merge.MergeConfig{
+		Collections: []string{"api", "worker", "proxy"},
+	}
+	opts := &types.CollectOpts{
+		Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	iter, err := merge.MergeCollect(t.Context(), cfg, opts, collect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv = append(recv, string(ent))
+	}
+
+	wants := []string{
+		`{"time":"2024-01-01T00:00:00Z","n":1,"_collection":"api"}`,
+		`{"n":2,"_collection":"api"}`,
+		`{"time":"2024-01-01T00:00:01Z","n":3,"_collection":"worker"}`,
+		`{"time":"2024-01-01T00:00:02Z","n":4,"_collection":"worker"}`,
+		`{"time":"2024-01-01T00:00:03Z","n":5,"_collection":"api"}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+	if !since.Equal(opts.Since) {
+		t.Fatalf("%s != %s", opts.Since, since)
+	}
+
+	cfg.Collections = []string{"api", "missing"}
+	if _, err := merge.MergeCollect(t.Context(), cfg, opts, collect); err == nil {
+		t.Fatal("must fail")
+	}
+}
+
+func TestMergeCollectTail(t *testing.T) {
+	cx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	chs := map[string]chan string{
+		"api":    make(chan string),
+		"worker": make(chan string),
+	}
+	collect := func(cx context.Context, name string, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+		ch := chs[name]
+		return func(yield func(json.RawMessage, error) bool) {
+			for {
+				select {
+				case <-cx.Done():
+					return
+				case line, ok := <-ch:
+					if !ok {
+						yield(nil, errors.New("broken"))
+						return
+					}
+					if !yield(json.RawMessage(line), nil) {
+						return
+					}
+				}
+			}
+		}, nil
+	}
+
+	cfg := &merge.MergeConfig{
+		Collections:     []string{"api", "worker"},
+		ReorderWindow:   "200ms",
+		CollectionField: "src",
+	}
+	opts := &types.CollectOpts{
+		Tail: true,
+	}
+	iter, err := merge.MergeCollect(cx, cfg, opts, collect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		// Arrives late, but ordered within the window.
+		chs["api"] <- `{"time":"2024-01-01T00:00:02Z","n":2}`
+		chs["worker"] <- `{"time":"2024-01-01T00:00:01Z","n":1}`
+		time.Sleep(500 * time.Millisecond)
+		// Out of the window.
+		chs["worker"] <- `{"time":"2024-01-01T00:00:00Z","n":3}`
+		time.Sleep(500 * time.Millisecond)
+		close(chs["api"])
+	}()
+
+	recv := []string{}
+	var last error
+	for ent, err := range iter {
+		if err != nil {
+			last = err
+			continue
+		}
+
+		recv = append(recv, string(ent))
+	}
+
+	wants := []string{
+		`{"time":"2024-01-01T00:00:01Z","n":1,"src":"worker"}`,
+		`{"time":"2024-01-01T00:00:02Z","n":2,"src":"api"}`,
+		`{"time":"2024-01-01T00:00:00Z","n":3,"src":"worker"}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+	if last == nil || last.Error() != "api: broken" {
+		t.Fatalf("unexpected error: %v", last)
+	}
+}
+
+func TestStreams(t *testing.T) {
+	stream := func(key string, follow bool, entries ...*merge.Entry) merge.Stream {
+		return merge.Stream{
+			Key: key,
+			Open: func(cx context.Context, since time.Time) iter.Seq2[*merge.Entry, error] {
+				return func(yield func(*merge.Entry, error) bool) {
+					for _, e := range entries {
+						if !yield(e, nil) {
+							return
+						}
+					}
+					if follow {
+						// Until the iteration ends.
+						<-cx.Done()
+					}
+				}
+			},
+		}
+	}
+	at := func(sec int) time.Time {
+		return time.Date(2024, 1, 1, 0, 0, sec, 0, time.UTC)
+	}
+
+	t.Run("history", func(t *testing.T) {
+		list := func(cx context.Context) ([]merge.Stream, error) {
+			return []merge.Stream{
+				stream("a", false,
+					&merge.Entry{Raw: json.RawMessage(`{"time":"2024-01-01T00:00:00Z","n":1}`)},
+					&merge.Entry{Raw: json.RawMessage(`{"time":"2024-01-01T00:00:03Z","n":4}`)},
+				),
+				// By the time of the stream rather than the record.
+				stream("b", false,
+					&merge.Entry{Raw: json.RawMessage(`{"n":2}`), Time: at(1)},
+					&merge.Entry{Raw: json.RawMessage(`plain`), Time: at(2)},
+				),
+			}, nil
+		}
+		seq, err := merge.Streams(t.Context(), false, list)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		for ent, err := range seq {
+			if err != nil {
+				t.Fatal(err)
+			}
+			recv = append(recv, string(ent))
+		}
+
+		wants := []string{
+			`{"time":"2024-01-01T00:00:00Z","n":1}`,
+			`{"n":2}`,
+			`plain`,
+			`{"time":"2024-01-01T00:00:03Z","n":4}`,
+		}
+		if !slices.Equal(wants, recv) {
+			t.Fatalf("%#v != %#v", wants, recv)
+		}
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		interval := *merge.ListInterval
+		*merge.ListInterval = 10 * time.Millisecond
+		t.Cleanup(func() {
+			*merge.ListInterval = interval
+		})
+
+		// Started later, and listed again while running.
+		var listed int
+		sinces := make(chan time.Time, 1)
+		list := func(cx context.Context) ([]merge.Stream, error) {
+			listed++
+			streams := []merge.Stream{
+				stream("a", true, &merge.Entry{Raw: json.RawMessage(`{"n":1}`)}),
+			}
+			if listed > 1 {
+				b := stream("b", true, &merge.Entry{Raw: json.RawMessage(`{"n":2}`)})
+				open := b.Open
+				b.Open = func(cx context.Context, since time.Time) iter.Seq2[*merge.Entry, error] {
+					sinces <- since
+					return open(cx, since)
+				}
+				streams = append(streams, b)
+			}
+			return streams, nil
+		}
+
+		start := time.Now()
+		seq, err := merge.Streams(t.Context(), true, list)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		for ent, err := range seq {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recv = append(recv, string(ent))
+			if len(recv) == 2 {
+				break
+			}
+		}
+
+		wants := []string{`{"n":1}`, `{"n":2}`}
+		if !slices.Equal(wants, recv) {
+			t.Fatalf("%#v != %#v", wants, recv)
+		}
+		if since := <-sinces; since.Before(start) || since.After(time.Now()) {
+			t.Fatalf("unexpected since: %s", since)
+		}
+	})
+}
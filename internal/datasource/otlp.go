@@ -0,0 +1,56 @@
+//go:build !no_otlp
+
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/otlp"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type otlpDatasource struct{}
+
+func (d *otlpDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	return collectReceived(cx, env, opts)
+}
+
+func (d *otlpDatasource) listen(cx context.Context, env *datasourceEnv, w io.Writer) (func() error, error) {
+	// Records are pushed by ReceiveOtlpLogs.
+	return nil, nil
+}
+
+func init() {
+	registerDatasource("otlp", new(otlpDatasource))
+}
+
+// Append OTLP/HTTP logs to the `otlp` collection. If name is empty, to all `otlp` collections.
+func ReceiveOtlpLogs(cx context.Context, cfg *config.Config, name, contentType string, body []byte) error {
+	targets, err := receiverTargets(cx, cfg, name, "otlp")
+	if err != nil {
+		return err
+	}
+
+	lines, err := otlp.DecodeLogs(body, contentType)
+	if errors.Is(err, otlp.ErrUnsupportedContentType) {
+		return ErrUnsupportedMediaType
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		for _, line := range lines {
+			if _, err := target.w.Write(line); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"mime"
+	"strconv"
+	"time"
+)
+
+// https://opentelemetry.io/docs/specs/otlp/#otlphttp
+
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+const (
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeJson     = "application/json"
+)
+
+// int64 is encoded as either a number or a decimal string in OTLP/JSON.
+type int64Value int64
+
+func (v *int64Value) UnmarshalJSON(b []byte) error {
+	text := string(bytes.Trim(b, `"`))
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return err
+	}
+	*v = int64Value(n)
+	return nil
+}
+
+type uint64Value uint64
+
+func (v *uint64Value) UnmarshalJSON(b []byte) error {
+	text := string(bytes.Trim(b, `"`))
+	n, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return err
+	}
+	*v = uint64Value(n)
+	return nil
+}
+
+// trace_id / span_id are encoded as hex strings in OTLP/JSON.
+type hexBytes []byte
+
+func (v *hexBytes) UnmarshalJSON(b []byte) error {
+	var text string
+	if err := json.Unmarshal(b, &text); err != nil {
+		return err
+	}
+	d, err := hex.DecodeString(text)
+	if err != nil {
+		return err
+	}
+	*v = d
+	return nil
+}
+
+type anyValue struct {
+	StringValue *string       `json:"stringValue"`
+	BoolValue   *bool         `json:"boolValue"`
+	IntValue    *int64Value   `json:"intValue"`
+	DoubleValue *float64      `json:"doubleValue"`
+	ArrayValue  *arrayValue   `json:"arrayValue"`
+	KvlistValue *keyValueList `json:"kvlistValue"`
+	BytesValue  []byte        `json:"bytesValue"`
+}
+
+type arrayValue struct {
+	Values []*anyValue `json:"values"`
+}
+
+type keyValueList struct {
+	Values []*keyValue `json:"values"`
+}
+
+type keyValue struct {
+	Key   string    `json:"key"`
+	Value *anyValue `json:"value"`
+}
+
+type resource struct {
+	Attributes []*keyValue `json:"attributes"`
+}
+
+type instrumentationScope struct {
+	Name       string      `json:"name"`
+	Version    string      `json:"version"`
+	Attributes []*keyValue `json:"attributes"`
+}
+
+type logRecord struct {
+	TimeUnixNano         uint64Value `json:"timeUnixNano"`
+	ObservedTimeUnixNano uint64Value `json:"observedTimeUnixNano"`
+	SeverityNumber       int         `json:"severityNumber"`
+	SeverityText         string      `json:"severityText"`
+	Body                 *anyValue   `json:"body"`
+	Attributes           []*keyValue `json:"attributes"`
+	Flags                uint32      `json:"flags"`
+	TraceId              hexBytes    `json:"traceId"`
+	SpanId               hexBytes    `json:"spanId"`
+	EventName            string      `json:"eventName"`
+}
+
+type scopeLogs struct {
+	Scope      *instrumentationScope `json:"scope"`
+	LogRecords []*logRecord          `json:"logRecords"`
+}
+
+type resourceLogs struct {
+	Resource  *resource    `json:"resource"`
+	ScopeLogs []*scopeLogs `json:"scopeLogs"`
+}
+
+type exportLogsServiceRequest struct {
+	ResourceLogs []*resourceLogs `json:"resourceLogs"`
+}
+
+func (v *anyValue) value() any {
+	if v == nil {
+		return nil
+	}
+
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return int64(*v.IntValue)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.ArrayValue != nil:
+		result := make([]any, 0, len(v.ArrayValue.Values))
+		for _, item := range v.ArrayValue.Values {
+			result = append(result, item.value())
+		}
+		return result
+	case v.KvlistValue != nil:
+		return attributes(v.KvlistValue.Values)
+	case v.BytesValue != nil:
+		// base64 by encoding/json
+		return v.BytesValue
+	}
+
+	return nil
+}
+
+func attributes(kvs []*keyValue) map[string]any {
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	result := make(map[string]any, len(kvs))
+	for _, kv := range kvs {
+		result[kv.Key] = kv.Value.value()
+	}
+	return result
+}
+
+func formatUnixNano(n uint64Value) string {
+	if n == 0 {
+		return ""
+	}
+
+	return time.Unix(0, int64(n)).UTC().Format(time.RFC3339Nano)
+}
+
+type flatScope struct {
+	Name       string         `json:"name,omitempty"`
+	Version    string         `json:"version,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+type flatRecord struct {
+	Timestamp         string         `json:"timestamp,omitempty"`
+	ObservedTimestamp string         `json:"observed_timestamp,omitempty"`
+	SeverityNumber    int            `json:"severity_number,omitempty"`
+	SeverityText      string         `json:"severity_text,omitempty"`
+	EventName         string         `json:"event_name,omitempty"`
+	Body              any            `json:"body,omitempty"`
+	Attributes        map[string]any `json:"attributes,omitempty"`
+	Resource          map[string]any `json:"resource,omitempty"`
+	Scope             *flatScope     `json:"scope,omitempty"`
+	TraceId           string         `json:"trace_id,omitempty"`
+	SpanId            string         `json:"span_id,omitempty"`
+	Flags             uint32         `json:"flags,omitempty"`
+}
+
+func flatten(req *exportLogsServiceRequest) ([][]byte, error) {
+	result := make([][]byte, 0)
+
+	for _, rl := range req.ResourceLogs {
+		var res map[string]any
+		if rl.Resource != nil {
+			res = attributes(rl.Resource.Attributes)
+		}
+
+		for _, sl := range rl.ScopeLogs {
+			var scope *flatScope
+			if sl.Scope != nil && (sl.Scope.Name != "" || sl.Scope.Version != "" || len(sl.Scope.Attributes) > 0) {
+				scope = &flatScope{
+					Name:       sl.Scope.Name,
+					Version:    sl.Scope.Version,
+					Attributes: attributes(sl.Scope.Attributes),
+				}
+			}
+
+			for _, r := range sl.LogRecords {
+				flat := &flatRecord{
+					Timestamp:         formatUnixNano(r.TimeUnixNano),
+					ObservedTimestamp: formatUnixNano(r.ObservedTimeUnixNano),
+					SeverityNumber:    r.SeverityNumber,
+					SeverityText:      r.SeverityText,
+					EventName:         r.EventName,
+					Body:              r.Body.value(),
+					Attributes:        attributes(r.Attributes),
+					Resource:          res,
+					Scope:             scope,
+					TraceId:           hex.EncodeToString(r.TraceId),
+					SpanId:            hex.EncodeToString(r.SpanId),
+					Flags:             r.Flags,
+				}
+				if flat.Timestamp == "" {
+					// https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-observedtimestamp
+					flat.Timestamp = flat.ObservedTimestamp
+				}
+
+				buf := new(bytes.Buffer)
+				enc := json.NewEncoder(buf)
+				enc.SetEscapeHTML(false)
+				if err := enc.Encode(flat); err != nil {
+					return nil, err
+				}
+				result = append(result, buf.Bytes())
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Decode ExportLogsServiceRequest to JSON lines, one per LogRecord.
+func DecodeLogs(body []byte, contentType string) ([][]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrUnsupportedContentType
+	}
+
+	var req *exportLogsServiceRequest
+	switch mediaType {
+	case ContentTypeProtobuf:
+		req, err = decodeRequestProto(body)
+	case ContentTypeJson:
+		req = new(exportLogsServiceRequest)
+		err = json.Unmarshal(body, req)
+	default:
+		return nil, ErrUnsupportedContentType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return flatten(req)
+}
@@ -0,0 +1,105 @@
+package otlp_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/otlp"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func message(fields ...[]byte) []byte {
+	return slices.Concat(fields...)
+}
+
+func bytesField(num protowire.Number, b []byte) []byte {
+	buf := protowire.AppendTag(nil, num, protowire.BytesType)
+	return protowire.AppendBytes(buf, b)
+}
+
+func stringField(num protowire.Number, s string) []byte {
+	return bytesField(num, []byte(s))
+}
+
+func varintField(num protowire.Number, v uint64) []byte {
+	buf := protowire.AppendTag(nil, num, protowire.VarintType)
+	return protowire.AppendVarint(buf, v)
+}
+
+func fixed64Field(num protowire.Number, v uint64) []byte {
+	buf := protowire.AppendTag(nil, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(buf, v)
+}
+
+func keyValue(key string, value []byte) []byte {
+	return message(stringField(1, key), bytesField(2, value))
+}
+
+const wants = `{"timestamp":"2024-01-01T00:00:00Z","severity_number":9,"severity_text":"INFO","body":"hello <world>","attributes":{"count":3,"tags":["a",true]},"resource":{"service.name":"api"},"scope":{"name":"my.logger"},"trace_id":"0102030405060708090a0b0c0d0e0f10"}
+`
+
+func TestDecodeLogsProtobuf(t *testing.T) {
+	record := message(
+		fixed64Field(1, 1704067200000000000),
+		varintField(2, 9),
+		stringField(3, "INFO"),
+		bytesField(5, stringField(1, "hello <world>")),
+		bytesField(6, keyValue("count", varintField(3, 3))),
+		bytesField(6, keyValue("tags", bytesField(5, message(
+			bytesField(1, stringField(1, "a")),
+			bytesField(1, varintField(2, 1)),
+		)))),
+		bytesField(9, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}),
+		// unknown field
+		varintField(99, 1),
+	)
+	req := bytesField(1, message(
+		bytesField(1, bytesField(1, keyValue("service.name", stringField(1, "api")))),
+		bytesField(2, message(
+			bytesField(1, stringField(1, "my.logger")),
+			bytesField(2, record),
+		)),
+	))
+
+	lines, err := otlp.DecodeLogs(req, "application/x-protobuf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lines) != 1 || string(lines[0]) != wants {
+		t.Fatalf("%q != %q", lines, wants)
+	}
+}
+
+func TestDecodeLogsJson(t *testing.T) {
+	req := `{"resourceLogs":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+		"scopeLogs":[{
+			"scope":{"name":"my.logger"},
+			"logRecords":[{
+				"timeUnixNano":"1704067200000000000",
+				"severityNumber":9,
+				"severityText":"INFO",
+				"body":{"stringValue":"hello <world>"},
+				"attributes":[
+					{"key":"count","value":{"intValue":"3"}},
+					{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{"boolValue":true}]}}}
+				],
+				"traceId":"0102030405060708090a0b0c0d0e0f10"
+			}]
+		}]
+	}]}`
+
+	lines, err := otlp.DecodeLogs([]byte(req), "application/json; charset=utf-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lines) != 1 || string(lines[0]) != wants {
+		t.Fatalf("%q != %q", lines, wants)
+	}
+
+	if _, err := otlp.DecodeLogs([]byte(req), "text/plain"); err != otlp.ErrUnsupportedContentType {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,385 @@
+package otlp
+
+import (
+	"errors"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Minimal decoder of the protobuf encoding. Unknown fields are skipped.
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/logs/v1/logs.proto
+
+var errInvalidProto = errors.New("invalid protobuf message")
+
+func eachField(b []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, err := fn(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+
+	return nil
+}
+
+func consumeBytes(typ protowire.Type, b []byte) ([]byte, int, error) {
+	if typ != protowire.BytesType {
+		return nil, 0, errInvalidProto
+	}
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return nil, 0, protowire.ParseError(n)
+	}
+	return v, n, nil
+}
+
+func consumeVarint(typ protowire.Type, b []byte) (uint64, int, error) {
+	if typ != protowire.VarintType {
+		return 0, 0, errInvalidProto
+	}
+	v, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return 0, 0, protowire.ParseError(n)
+	}
+	return v, n, nil
+}
+
+func consumeFixed64(typ protowire.Type, b []byte) (uint64, int, error) {
+	if typ != protowire.Fixed64Type {
+		return 0, 0, errInvalidProto
+	}
+	v, n := protowire.ConsumeFixed64(b)
+	if n < 0 {
+		return 0, 0, protowire.ParseError(n)
+	}
+	return v, n, nil
+}
+
+func consumeFixed32(typ protowire.Type, b []byte) (uint32, int, error) {
+	if typ != protowire.Fixed32Type {
+		return 0, 0, errInvalidProto
+	}
+	v, n := protowire.ConsumeFixed32(b)
+	if n < 0 {
+		return 0, 0, protowire.ParseError(n)
+	}
+	return v, n, nil
+}
+
+func decodeAnyValueProto(b []byte) (*anyValue, error) {
+	v := new(anyValue)
+	err := eachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			s, n, err := consumeBytes(typ, b)
+			str := string(s)
+			v.StringValue = &str
+			return n, err
+		case 2:
+			x, n, err := consumeVarint(typ, b)
+			bv := x != 0
+			v.BoolValue = &bv
+			return n, err
+		case 3:
+			x, n, err := consumeVarint(typ, b)
+			iv := int64Value(x)
+			v.IntValue = &iv
+			return n, err
+		case 4:
+			x, n, err := consumeFixed64(typ, b)
+			f := math.Float64frombits(x)
+			v.DoubleValue = &f
+			return n, err
+		case 5:
+			s, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			arr := new(arrayValue)
+			err = eachField(s, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+				if num != 1 {
+					return 0, nil
+				}
+				s, n, err := consumeBytes(typ, b)
+				if err != nil {
+					return 0, err
+				}
+				item, err := decodeAnyValueProto(s)
+				if err != nil {
+					return 0, err
+				}
+				arr.Values = append(arr.Values, item)
+				return n, nil
+			})
+			v.ArrayValue = arr
+			return n, err
+		case 6:
+			s, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			kvs := new(keyValueList)
+			err = eachField(s, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+				if num != 1 {
+					return 0, nil
+				}
+				s, n, err := consumeBytes(typ, b)
+				if err != nil {
+					return 0, err
+				}
+				kv, err := decodeKeyValueProto(s)
+				if err != nil {
+					return 0, err
+				}
+				kvs.Values = append(kvs.Values, kv)
+				return n, nil
+			})
+			v.KvlistValue = kvs
+			return n, err
+		case 7:
+			s, n, err := consumeBytes(typ, b)
+			v.BytesValue = append([]byte{}, s...)
+			return n, err
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func decodeKeyValueProto(b []byte) (*keyValue, error) {
+	kv := new(keyValue)
+	err := eachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			s, n, err := consumeBytes(typ, b)
+			kv.Key = string(s)
+			return n, err
+		case 2:
+			s, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			kv.Value, err = decodeAnyValueProto(s)
+			return n, err
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return kv, nil
+}
+
+// Append the repeated KeyValue field.
+func appendKeyValueProto(dst []*keyValue, typ protowire.Type, b []byte) ([]*keyValue, int, error) {
+	s, n, err := consumeBytes(typ, b)
+	if err != nil {
+		return nil, 0, err
+	}
+	kv, err := decodeKeyValueProto(s)
+	if err != nil {
+		return nil, 0, err
+	}
+	return append(dst, kv), n, nil
+}
+
+func decodeLogRecordProto(b []byte) (*logRecord, error) {
+	r := new(logRecord)
+	err := eachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			x, n, err := consumeFixed64(typ, b)
+			r.TimeUnixNano = uint64Value(x)
+			return n, err
+		case 2:
+			x, n, err := consumeVarint(typ, b)
+			r.SeverityNumber = int(x)
+			return n, err
+		case 3:
+			s, n, err := consumeBytes(typ, b)
+			r.SeverityText = string(s)
+			return n, err
+		case 5:
+			s, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			r.Body, err = decodeAnyValueProto(s)
+			return n, err
+		case 6:
+			var n int
+			var err error
+			r.Attributes, n, err = appendKeyValueProto(r.Attributes, typ, b)
+			return n, err
+		case 8:
+			x, n, err := consumeFixed32(typ, b)
+			r.Flags = x
+			return n, err
+		case 9:
+			s, n, err := consumeBytes(typ, b)
+			r.TraceId = hexBytes(s)
+			return n, err
+		case 10:
+			s, n, err := consumeBytes(typ, b)
+			r.SpanId = hexBytes(s)
+			return n, err
+		case 11:
+			x, n, err := consumeFixed64(typ, b)
+			r.ObservedTimeUnixNano = uint64Value(x)
+			return n, err
+		case 12:
+			s, n, err := consumeBytes(typ, b)
+			r.EventName = string(s)
+			return n, err
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func decodeScopeProto(b []byte) (*instrumentationScope, error) {
+	scope := new(instrumentationScope)
+	err := eachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			s, n, err := consumeBytes(typ, b)
+			scope.Name = string(s)
+			return n, err
+		case 2:
+			s, n, err := consumeBytes(typ, b)
+			scope.Version = string(s)
+			return n, err
+		case 3:
+			var n int
+			var err error
+			scope.Attributes, n, err = appendKeyValueProto(scope.Attributes, typ, b)
+			return n, err
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return scope, nil
+}
+
+func decodeScopeLogsProto(b []byte) (*scopeLogs, error) {
+	sl := new(scopeLogs)
+	err := eachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			s, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			sl.Scope, err = decodeScopeProto(s)
+			return n, err
+		case 2:
+			s, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			r, err := decodeLogRecordProto(s)
+			if err != nil {
+				return 0, err
+			}
+			sl.LogRecords = append(sl.LogRecords, r)
+			return n, nil
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sl, nil
+}
+
+func decodeResourceLogsProto(b []byte) (*resourceLogs, error) {
+	rl := new(resourceLogs)
+	err := eachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			s, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			rl.Resource = new(resource)
+			err = eachField(s, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+				if num != 1 {
+					return 0, nil
+				}
+				var n int
+				var err error
+				rl.Resource.Attributes, n, err = appendKeyValueProto(rl.Resource.Attributes, typ, b)
+				return n, err
+			})
+			return n, err
+		case 2:
+			s, n, err := consumeBytes(typ, b)
+			if err != nil {
+				return 0, err
+			}
+			sl, err := decodeScopeLogsProto(s)
+			if err != nil {
+				return 0, err
+			}
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+			return n, nil
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rl, nil
+}
+
+func decodeRequestProto(b []byte) (*exportLogsServiceRequest, error) {
+	req := new(exportLogsServiceRequest)
+	err := eachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != 1 {
+			return 0, nil
+		}
+		s, n, err := consumeBytes(typ, b)
+		if err != nil {
+			return 0, err
+		}
+		rl, err := decodeResourceLogsProto(s)
+		if err != nil {
+			return 0, err
+		}
+		req.ResourceLogs = append(req.ResourceLogs, rl)
+		return n, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
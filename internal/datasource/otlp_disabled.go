@@ -0,0 +1,13 @@
+//go:build no_otlp
+
+package datasource
+
+import (
+	"context"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+)
+
+func ReceiveOtlpLogs(cx context.Context, cfg *config.Config, name, contentType string, body []byte) error {
+	return ErrCollectionNotFound
+}
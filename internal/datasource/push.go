@@ -0,0 +1,14 @@
+package datasource
+
+import (
+	"errors"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/ingest"
+)
+
+// Entry points for receiver collections fed over HTTP. Each is defined in the file of its datasource type,
+// or in its `_disabled.go` counterpart when the type is excluded by a build tag.
+
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+type IngestResult = ingest.IngestResult
@@ -0,0 +1,189 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/config"
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/stdin"
+	"github.com/ysuzuki-bysystems/seigo/internal/scrollbuffer"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+var ContextReceiversKey = &struct{}{}
+
+// Datasource which receives logs by itself, instead of fetching them on collect.
+// Received records are written as JSON lines into a scroll buffer per collection, and collected from it like `stdin`.
+type receiver interface {
+	datasource
+
+	// Bind listeners, and return a function which receives logs until the context is done.
+	// nil function for receivers without listeners.
+	listen(context.Context, *datasourceEnv, io.Writer) (func() error, error)
+}
+
+type receiverConfig struct {
+	BufferEntrySize int `json:"buffer-entry-size"`
+	BufferEntries   int `json:"buffer-entries"`
+}
+
+type receiverBuffer struct {
+	buf *scrollbuffer.ScrollBuffer
+
+	// Lines from concurrent senders must not be interleaved.
+	mu sync.Mutex
+	w  *scrollbuffer.Writer
+}
+
+func (r *receiverBuffer) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.w.Write(b)
+}
+
+type Receivers struct {
+	bufs map[string]*receiverBuffer
+}
+
+// Start all receiver collections in cfg. They stop when cx is done.
+// Fails if any of them cannot listen. The started ones stop with cx.
+func StartReceivers(cx context.Context, cfg *config.Config, dir string, wg *sync.WaitGroup) (*Receivers, error) {
+	receivers := &Receivers{
+		bufs: make(map[string]*receiverBuffer),
+	}
+
+	for _, collection := range cfg.Collection {
+		v, found := datasources.Load(collection.Type)
+		if !found {
+			continue
+		}
+
+		r, ok := v.(receiver)
+		if !ok {
+			continue
+		}
+
+		env := &datasourceEnv{
+			cfg:  collection.Opts,
+			path: cfg.Path,
+			name: collection.Name,
+		}
+
+		var rcfg receiverConfig
+		if err := env.unmarshalConfig(&rcfg); err != nil {
+			return nil, err
+		}
+		if rcfg.BufferEntrySize <= 0 {
+			rcfg.BufferEntrySize = 8192
+		}
+		if rcfg.BufferEntries < 2 {
+			rcfg.BufferEntries = 10
+		}
+
+		buf, err := scrollbuffer.New(dir, rcfg.BufferEntrySize, rcfg.BufferEntries)
+		if err != nil {
+			return nil, err
+		}
+		rb := &receiverBuffer{
+			buf: buf,
+			w:   buf.NewWriter(),
+		}
+		shutdown := func() {
+			_ = rb.w.Close()
+
+			cx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := buf.Shutdown(cx); err != nil {
+				slog.Warn("failed to Shutdown", "error", err)
+			}
+		}
+
+		serve, err := r.listen(cx, env, rb)
+		if err != nil {
+			shutdown()
+			return nil, fmt.Errorf("%s: %w", collection.Name, err)
+		}
+		receivers.bufs[collection.Name] = rb
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if serve != nil {
+				err := serve()
+				if err != nil && !errors.Is(err, scrollbuffer.ErrClosed) {
+					slog.Warn("failed to receive", "collection", collection.Name, "error", err)
+				}
+			}
+
+			// Wait for shutdown. Collected records are still available until then.
+			<-cx.Done()
+			shutdown()
+		}()
+	}
+
+	return receivers, nil
+}
+
+func collectReceived(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	receivers, ok := cx.Value(ContextReceiversKey).(*Receivers)
+	if !ok {
+		return nil, errors.New("receivers are not started. Available only while serving")
+	}
+
+	rb, ok := receivers.bufs[env.name]
+	if !ok {
+		return nil, fmt.Errorf("receiver not found: %s", env.name)
+	}
+
+	// Records are already converted on receive.
+	return stdin.StdinCollect(cx, rb.buf, new(stdin.StdinConfig), opts)
+}
+
+type receiverTarget struct {
+	w   io.Writer
+	env *datasourceEnv
+}
+
+// Receiver collections of typ. If name is empty, all collections of typ.
+func receiverTargets(cx context.Context, cfg *config.Config, name, typ string) ([]*receiverTarget, error) {
+	receivers, ok := cx.Value(ContextReceiversKey).(*Receivers)
+	if !ok {
+		return nil, errors.New("receivers are not started")
+	}
+
+	result := make([]*receiverTarget, 0)
+	for _, item := range cfg.Collection {
+		if item.Type != typ || (name != "" && item.Name != name) {
+			continue
+		}
+
+		rb, ok := receivers.bufs[item.Name]
+		if !ok {
+			return nil, fmt.Errorf("receiver not found: %s", item.Name)
+		}
+		result = append(result, &receiverTarget{
+			w: rb,
+			env: &datasourceEnv{
+				cfg:  item.Opts,
+				path: cfg.Path,
+				name: item.Name,
+			},
+		})
+	}
+
+	if len(result) == 0 {
+		return nil, ErrCollectionNotFound
+	}
+
+	return result, nil
+}
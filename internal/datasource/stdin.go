@@ -21,7 +21,12 @@ func (d *stdinDatasource) collect(cx context.Context, env *datasourceEnv, opts *
 		return nil, fmt.Errorf("use --stdin flag")
 	}
 
-	return stdin.StdinCollect(cx, buf, opts)
+	var cfg stdin.StdinConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return stdin.StdinCollect(cx, buf, &cfg, opts)
 }
 
 func init() {
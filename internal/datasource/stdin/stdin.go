@@ -4,26 +4,50 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"iter"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+	"github.com/ysuzuki-bysystems/seigo/internal/record"
 	"github.com/ysuzuki-bysystems/seigo/internal/scrollbuffer"
 	"github.com/ysuzuki-bysystems/seigo/internal/types"
 )
 
-func iterRecords(stdin io.Reader) iter.Seq2[json.RawMessage, error] {
+type StdinConfig struct {
+	parser.Config
+}
+
+// offset is the position of stdin in the scroll buffer. Cursors are the offsets after each line.
+// Ends at the first record after until, unless it is zero.
+func iterRecords(conv *parser.Converter, stdin io.Reader, offset int64, until time.Time, cursor func(offset int64)) iter.Seq2[json.RawMessage, error] {
 	return func(yield func(json.RawMessage, error) bool) {
 		scanner := bufio.NewScanner(stdin)
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, token, err := bufio.ScanLines(data, atEOF)
+			offset += int64(advance)
+			return advance, token, err
+		})
 		for scanner.Scan() {
 			line := scanner.Bytes()
 
-			var raw json.RawMessage
-			err := json.Unmarshal(line, &raw)
+			raw, err := conv.Convert(line)
 			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if raw == nil {
 				// drop & skip
 				continue
 			}
+			if ts, ok := record.Timestamp(raw); ok && !until.IsZero() && ts.After(until) {
+				return
+			}
 
+			cursor(offset)
 			if !yield(raw, nil) {
 				return
 			}
@@ -31,11 +55,43 @@ func iterRecords(stdin io.Reader) iter.Seq2[json.RawMessage, error] {
 	}
 }
 
-func StdinCollect(cx context.Context, buf *scrollbuffer.ScrollBuffer, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
-	r := buf.NewReader(opts.Tail)
+// `<buffer id>:<offset>`
+func newReader(buf *scrollbuffer.ScrollBuffer, opts *types.CollectOpts) *scrollbuffer.Reader {
+	id, offset, ok := strings.Cut(opts.After, ":")
+	if !ok || id != buf.Id() {
+		return buf.NewReader(opts.Tail)
+	}
+
+	n, err := strconv.ParseInt(offset, 10, 64)
+	if err != nil {
+		return buf.NewReader(opts.Tail)
+	}
+
+	r, err := buf.NewReaderAt(n, opts.Tail)
+	if err != nil {
+		// Discarded. Records in between are lost.
+		return buf.NewReader(opts.Tail)
+	}
+	return r
+}
+
+func StdinCollect(cx context.Context, buf *scrollbuffer.ScrollBuffer, cfg *StdinConfig, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	conv, err := parser.NewConverter(&cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newReader(buf, opts)
 	context.AfterFunc(cx, func() {
 		_ = r.Close()
 	})
 
-	return iterRecords(r), nil
+	cursor := func(offset int64) {
+		opts.Cursor(fmt.Sprintf("%s:%d", buf.Id(), offset))
+	}
+	until := opts.Until
+	if opts.Tail {
+		until = time.Time{}
+	}
+	return iterRecords(conv, r, r.Offset(), until, cursor), nil
 }
@@ -0,0 +1,113 @@
+package stdin_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/stdin"
+	"github.com/ysuzuki-bysystems/seigo/internal/scrollbuffer"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+func newBuffer(t *testing.T, data string) *scrollbuffer.ScrollBuffer {
+	t.Helper()
+
+	buf, err := scrollbuffer.New(t.TempDir(), 1024, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = buf.Shutdown(context.Background())
+	})
+
+	w := buf.NewWriter()
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func collectAll(t *testing.T, buf *scrollbuffer.ScrollBuffer, opts *types.CollectOpts) []string {
+	t.Helper()
+
+	iter, err := stdin.StdinCollect(t.Context(), buf, &stdin.StdinConfig{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recv := []string{}
+	for ent, err := range iter {
+		if err != nil {
+			t.Fatal(err)
+		}
+		recv = append(recv, string(ent))
+	}
+	return recv
+}
+
+func TestStdinCollectUntil(t *testing.T) {
+	buf := newBuffer(t, `{"time":"2024-01-01T00:00:00Z","n":1}
+{"n":2}
+{"time":"2024-01-01T00:00:02Z","n":3}
+{"time":"2024-01-01T00:00:00Z","n":4}
+`)
+
+	recv := collectAll(t, buf, &types.CollectOpts{
+		Until: time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC),
+	})
+
+	wants := []string{
+		`{"time":"2024-01-01T00:00:00Z","n":1}`,
+		`{"n":2}`,
+	}
+	if !slices.Equal(wants, recv) {
+		t.Fatalf("%#v != %#v", wants, recv)
+	}
+}
+
+func TestStdinCollectCursor(t *testing.T) {
+	buf := newBuffer(t, "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n")
+
+	cursors := []string{}
+	recv := collectAll(t, buf, &types.CollectOpts{
+		OnCursor: func(cursor string) {
+			cursors = append(cursors, cursor)
+		},
+	})
+	if len(recv) != 3 {
+		t.Fatalf("%#v", recv)
+	}
+
+	// `<buffer id>:<offset after the line>`
+	wants := []string{
+		fmt.Sprintf("%s:8", buf.Id()),
+		fmt.Sprintf("%s:16", buf.Id()),
+		fmt.Sprintf("%s:24", buf.Id()),
+	}
+	if !slices.Equal(wants, cursors) {
+		t.Fatalf("%#v != %#v", wants, cursors)
+	}
+
+	for _, tt := range []struct {
+		after string
+		wants []string
+	}{
+		{cursors[0], []string{`{"n":2}`, `{"n":3}`}},
+		{cursors[2], []string{}},
+		// Starts over.
+		{"other:8", []string{`{"n":1}`, `{"n":2}`, `{"n":3}`}},
+		{buf.Id() + ":x", []string{`{"n":1}`, `{"n":2}`, `{"n":3}`}},
+		{buf.Id() + ":100", []string{`{"n":1}`, `{"n":2}`, `{"n":3}`}},
+	} {
+		recv := collectAll(t, buf, &types.CollectOpts{After: tt.after})
+		if !slices.Equal(tt.wants, recv) {
+			t.Fatalf("%s: %#v != %#v", tt.after, tt.wants, recv)
+		}
+	}
+}
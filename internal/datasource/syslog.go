@@ -0,0 +1,32 @@
+//go:build !no_syslog
+
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"iter"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/syslog"
+	"github.com/ysuzuki-bysystems/seigo/internal/types"
+)
+
+type syslogDatasource struct{}
+
+func (d *syslogDatasource) collect(cx context.Context, env *datasourceEnv, opts *types.CollectOpts) (iter.Seq2[json.RawMessage, error], error) {
+	return collectReceived(cx, env, opts)
+}
+
+func (d *syslogDatasource) listen(cx context.Context, env *datasourceEnv, w io.Writer) (func() error, error) {
+	var cfg syslog.SyslogConfig
+	if err := env.unmarshalConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return syslog.SyslogListen(cx, &cfg, w)
+}
+
+func init() {
+	registerDatasource("syslog", new(syslogDatasource))
+}
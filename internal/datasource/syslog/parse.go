@@ -0,0 +1,259 @@
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type message struct {
+	Facility       int                          `json:"facility"`
+	Severity       int                          `json:"severity"`
+	Timestamp      string                       `json:"timestamp,omitempty"`
+	Hostname       string                       `json:"hostname,omitempty"`
+	AppName        string                       `json:"app_name,omitempty"`
+	ProcId         string                       `json:"procid,omitempty"`
+	MsgId          string                       `json:"msgid,omitempty"`
+	StructuredData map[string]map[string]string `json:"structured_data,omitempty"`
+	Message        string                       `json:"message"`
+}
+
+var errMalformed = errors.New("malformed syslog message")
+
+// Current time for the year-less BSD timestamp.
+var now = time.Now
+
+func parsePri(b []byte) (int, []byte, error) {
+	if len(b) < 3 || b[0] != '<' {
+		return 0, nil, errMalformed
+	}
+
+	end := bytes.IndexByte(b[:min(len(b), 5)], '>')
+	if end < 2 {
+		return 0, nil, errMalformed
+	}
+
+	pri, err := strconv.Atoi(string(b[1:end]))
+	if err != nil || pri > 191 {
+		return 0, nil, errMalformed
+	}
+
+	return pri, b[end+1:], nil
+}
+
+func parse(b []byte) (*message, error) {
+	b = bytes.TrimRight(b, "\r\n\x00")
+
+	pri, rest, err := parsePri(b)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &message{
+		Facility: pri / 8,
+		Severity: pri % 8,
+	}
+
+	// RFC 5424 starts with VERSION SP.
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' && rest[1] == ' ' {
+		if err := parse5424(msg, rest[2:]); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+
+	parse3164(msg, rest)
+	return msg, nil
+}
+
+func nextField(b []byte) (string, []byte, error) {
+	i := bytes.IndexByte(b, ' ')
+	if i < 0 {
+		if len(b) == 0 {
+			return "", nil, errMalformed
+		}
+		return nilValue(string(b)), nil, nil
+	}
+
+	return nilValue(string(b[:i])), b[i+1:], nil
+}
+
+// NILVALUE "-"
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// https://datatracker.ietf.org/doc/html/rfc5424#section-6
+func parse5424(msg *message, b []byte) error {
+	var timestamp string
+	var err error
+	timestamp, b, err = nextField(b)
+	if err != nil {
+		return err
+	}
+	if timestamp != "" {
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return errMalformed
+		}
+		msg.Timestamp = t.Format(time.RFC3339Nano)
+	}
+
+	for _, dst := range []*string{&msg.Hostname, &msg.AppName, &msg.ProcId, &msg.MsgId} {
+		*dst, b, err = nextField(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(b) == 0 {
+		return errMalformed
+	}
+
+	if b[0] == '-' {
+		b = b[1:]
+	} else {
+		msg.StructuredData, b, err = parseStructuredData(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(b) > 0 {
+		if b[0] != ' ' {
+			return errMalformed
+		}
+		b = b[1:]
+	}
+
+	b = bytes.TrimPrefix(b, []byte("\xEF\xBB\xBF"))
+	msg.Message = toValidUtf8(b)
+
+	return nil
+}
+
+func parseStructuredData(b []byte) (map[string]map[string]string, []byte, error) {
+	result := make(map[string]map[string]string)
+
+	for len(b) > 0 && b[0] == '[' {
+		b = b[1:]
+
+		end := bytes.IndexAny(b, " ]")
+		if end < 1 {
+			return nil, nil, errMalformed
+		}
+		params := make(map[string]string)
+		result[string(b[:end])] = params
+		b = b[end:]
+
+		for {
+			if len(b) == 0 {
+				return nil, nil, errMalformed
+			}
+			if b[0] == ']' {
+				b = b[1:]
+				break
+			}
+			if b[0] != ' ' {
+				return nil, nil, errMalformed
+			}
+			b = b[1:]
+
+			eq := bytes.Index(b, []byte("=\""))
+			if eq < 1 {
+				return nil, nil, errMalformed
+			}
+			name := string(b[:eq])
+			b = b[eq+2:]
+
+			// PARAM-VALUE: '"', '\' and ']' MUST be escaped.
+			var value strings.Builder
+			closed := false
+			for i := 0; i < len(b); i++ {
+				c := b[i]
+				if c == '\\' && i+1 < len(b) && (b[i+1] == '"' || b[i+1] == '\\' || b[i+1] == ']') {
+					value.WriteByte(b[i+1])
+					i++
+					continue
+				}
+				if c == '"' {
+					b = b[i+1:]
+					closed = true
+					break
+				}
+				value.WriteByte(c)
+			}
+			if !closed {
+				return nil, nil, errMalformed
+			}
+
+			params[name] = toValidUtf8([]byte(value.String()))
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, nil, errMalformed
+	}
+
+	return result, b, nil
+}
+
+// https://datatracker.ietf.org/doc/html/rfc3164#section-4.1.2
+func parse3164(msg *message, b []byte) {
+	const stampLen = len(time.Stamp)
+
+	if len(b) > stampLen && b[stampLen] == ' ' {
+		if t, err := time.ParseInLocation(time.Stamp, string(b[:stampLen]), time.Local); err == nil {
+			current := now()
+			t = t.AddDate(current.Year(), 0, 0)
+			if t.After(current.AddDate(0, 0, 1)) {
+				// Last year's message around new year.
+				t = t.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = t.Format(time.RFC3339Nano)
+			b = b[stampLen+1:]
+
+			if i := bytes.IndexByte(b, ' '); i > 0 {
+				msg.Hostname = string(b[:i])
+				b = b[i+1:]
+			}
+		}
+	}
+
+	// TAG[PID]: CONTENT
+	tagEnd := bytes.IndexAny(b, "[: ")
+	if tagEnd > 0 && tagEnd <= 48 {
+		tag := string(b[:tagEnd])
+		rest := b[tagEnd:]
+
+		if rest[0] == '[' {
+			if end := bytes.IndexByte(rest, ']'); end > 0 {
+				msg.ProcId = string(rest[1:end])
+				rest = rest[end+1:]
+			}
+		}
+
+		if bytes.HasPrefix(rest, []byte(": ")) || bytes.HasPrefix(rest, []byte(":")) {
+			msg.AppName = tag
+			b = bytes.TrimPrefix(rest[1:], []byte(" "))
+		} else {
+			msg.ProcId = ""
+		}
+	}
+
+	msg.Message = toValidUtf8(b)
+}
+
+func toValidUtf8(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+
+	return strings.ToValidUTF8(string(b), "�")
+}
@@ -0,0 +1,242 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+type SyslogConfig struct {
+	// e.g. `0.0.0.0:5514`
+	Address string `json:"address"`
+	// `udp` and / or `tcp`. Defaults to both.
+	Protocols []string `json:"protocols"`
+}
+
+// Maximum frame size to accept.
+const maxMessageSize = 64 * 1024
+
+// Of maxMessageSize.
+const maxFrameLengthDigits = 5
+
+func emit(w io.Writer, b []byte) error {
+	msg, err := parse(b)
+	if err != nil {
+		slog.Debug("drop syslog message", "error", err)
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(msg); err != nil {
+		return err
+	}
+
+	// Write as a whole line at once.
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// https://datatracker.ietf.org/doc/html/rfc6587#section-3.4
+// Octet-counting (`MSG-LEN SP SYSLOG-MSG`) or non-transparent-framing (LF terminated).
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] < '1' || first[0] > '9' {
+		line, err := r.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			// Too long. Emit the head and skip the rest.
+			head := slices.Clone(line)
+			for errors.Is(err, bufio.ErrBufferFull) {
+				_, err = r.ReadSlice('\n')
+			}
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return head, nil
+		}
+		if err != nil && (!errors.Is(err, io.EOF) || len(line) == 0) {
+			return nil, err
+		}
+		return slices.Clone(line), nil
+	}
+
+	// The octet count and a space. Read by byte, not to wait for more than the frame, nor to read a long run of digits.
+	digits := make([]byte, 0, maxFrameLengthDigits)
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if c == ' ' {
+			break
+		}
+		if c < '0' || c > '9' || len(digits) == maxFrameLengthDigits {
+			return nil, fmt.Errorf("invalid frame length: %q", append(digits, c))
+		}
+		digits = append(digits, c)
+	}
+	n, err := strconv.Atoi(string(digits))
+	if err != nil || n > maxMessageSize {
+		return nil, fmt.Errorf("invalid frame length: %q", digits)
+	}
+
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+func serveTcp(cx context.Context, listener net.Listener, w io.Writer) error {
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+
+			stop := context.AfterFunc(cx, func() {
+				_ = conn.Close()
+			})
+			defer stop()
+
+			r := bufio.NewReaderSize(conn, maxMessageSize)
+			for {
+				frame, err := readFrame(r)
+				if err != nil {
+					if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+						slog.Warn("failed to read syslog", "remote", conn.RemoteAddr(), "error", err)
+					}
+					return
+				}
+
+				if err := emit(w, frame); err != nil {
+					slog.Warn("failed to write syslog", "error", err)
+					return
+				}
+			}
+		}()
+	}
+}
+
+func serveUdp(conn net.PacketConn, w io.Writer) error {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		if err := emit(w, buf[:n]); err != nil {
+			return err
+		}
+	}
+}
+
+// Receive syslog messages and write them as JSON lines into w until cx is done.
+func SyslogReceive(cx context.Context, cfg *SyslogConfig, w io.Writer) error {
+	serve, err := SyslogListen(cx, cfg, w)
+	if err != nil {
+		return err
+	}
+	return serve()
+}
+
+// Bind the address. Messages are received by serve until cx is done.
+func SyslogListen(cx context.Context, cfg *SyslogConfig, w io.Writer) (serve func() error, err error) {
+	if cfg.Address == "" {
+		return nil, errors.New("empty address")
+	}
+
+	protocols := cfg.Protocols
+	if len(protocols) == 0 {
+		protocols = []string{"udp", "tcp"}
+	}
+
+	servers := make([]func() error, 0)
+	closers := make([]io.Closer, 0)
+	closeAll := func() {
+		for _, c := range closers {
+			_ = c.Close()
+		}
+	}
+
+	for _, proto := range protocols {
+		switch proto {
+		case "udp":
+			conn, err := net.ListenPacket("udp", cfg.Address)
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			closers = append(closers, conn)
+			servers = append(servers, func() error {
+				return serveUdp(conn, w)
+			})
+
+		case "tcp":
+			listener, err := net.Listen("tcp", cfg.Address)
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			closers = append(closers, listener)
+			servers = append(servers, func() error {
+				return serveTcp(cx, listener, w)
+			})
+
+		default:
+			closeAll()
+			return nil, fmt.Errorf("Unknown protocol: %s", proto)
+		}
+	}
+
+	return func() error {
+		stop := context.AfterFunc(cx, closeAll)
+		defer stop()
+
+		errs := make([]error, len(servers))
+		wg := &sync.WaitGroup{}
+		for i, serve := range servers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				if err := serve(); err != nil {
+					errs[i] = err
+					closeAll()
+				}
+			}()
+		}
+		wg.Wait()
+
+		return errors.Join(errs...)
+	}, nil
+}
@@ -0,0 +1,246 @@
+package syslog_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/datasource/syslog"
+)
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *lockedBuffer) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.buf.Write(b)
+}
+
+func (l *lockedBuffer) lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	text := strings.TrimSuffix(l.buf.String(), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+func TestSyslogReceive(t *testing.T) {
+	cx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	addr := freeAddr(t)
+	cfg := &syslog.SyslogConfig{
+		Address: addr,
+	}
+
+	w := new(lockedBuffer)
+	done := make(chan error, 1)
+	go func() {
+		done <- syslog.SyslogReceive(cx, cfg, w)
+	}()
+
+	dial := func(network string) net.Conn {
+		for {
+			conn, err := net.Dial(network, addr)
+			if err == nil {
+				return conn
+			}
+
+			select {
+			case <-cx.Done():
+				t.Fatal(err)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	// UDP is ready when TCP is.
+	tcp := dial("tcp")
+	defer tcp.Close()
+
+	waitFor := func(n int) []string {
+		for {
+			got := w.lines()
+			if len(got) >= n {
+				return got
+			}
+
+			select {
+			case <-cx.Done():
+				t.Fatalf("timeout: %#v", got)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	udp := dial("udp")
+	defer udp.Close()
+	// https://datatracker.ietf.org/doc/html/rfc5424#section-6.5
+	if _, err := udp.Write([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Appli\"cation"] An application event log entry...`)); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(1)
+
+	// octet-counting & non-transparent-framing
+	frame := "<34>1 - host app 123 - - \xEF\xBB\xBF{\"msg\":\"<hello>\"}"
+	if _, err := tcp.Write([]byte(fmt.Sprintf("%d %s<13>1 - - - - - -\n<13>not a syslog\n", len(frame), frame))); err != nil {
+		t.Fatal(err)
+	}
+
+	wants := []string{
+		`{"facility":20,"severity":5,"timestamp":"2003-10-11T22:14:15.003Z","hostname":"mymachine.example.com","app_name":"evntslog","msgid":"ID47","structured_data":{"exampleSDID@32473":{"eventSource":"Appli\"cation","iut":"3"}},"message":"An application event log entry..."}`,
+		`{"facility":4,"severity":2,"hostname":"host","app_name":"app","procid":"123","message":"{\"msg\":\"<hello>\"}"}`,
+		`{"facility":1,"severity":5,"message":""}`,
+		`{"facility":1,"severity":5,"message":"not a syslog"}`,
+	}
+
+	if got := waitFor(len(wants)); !slices.Equal(wants, got) {
+		t.Fatalf("%#v != %#v", wants, got)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyslogListenInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	cfg := &syslog.SyslogConfig{
+		Address:   listener.Addr().String(),
+		Protocols: []string{"tcp"},
+	}
+	if _, err := syslog.SyslogListen(t.Context(), cfg, new(lockedBuffer)); err == nil {
+		t.Fatal("must fail")
+	}
+}
+
+func TestSyslogReceiveLongFrameLength(t *testing.T) {
+	cx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	addr := freeAddr(t)
+	cfg := &syslog.SyslogConfig{
+		Address:   addr,
+		Protocols: []string{"tcp"},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- syslog.SyslogReceive(cx, cfg, new(lockedBuffer))
+	}()
+
+	var conn net.Conn
+	for {
+		var err error
+		if conn, err = net.Dial("tcp", addr); err == nil {
+			break
+		}
+
+		select {
+		case <-cx.Done():
+			t.Fatal(err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	defer conn.Close()
+
+	// Rejected without waiting for a space.
+	if _, err := conn.Write([]byte(strings.Repeat("1", 1024))); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	// EOF, or reset if unread.
+	if _, err := conn.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("must be closed: %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyslogReceiveShortFrame(t *testing.T) {
+	cx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	addr := freeAddr(t)
+	cfg := &syslog.SyslogConfig{
+		Address:   addr,
+		Protocols: []string{"tcp"},
+	}
+
+	w := new(lockedBuffer)
+	done := make(chan error, 1)
+	go func() {
+		done <- syslog.SyslogReceive(cx, cfg, w)
+	}()
+
+	var conn net.Conn
+	for {
+		var err error
+		if conn, err = net.Dial("tcp", addr); err == nil {
+			break
+		}
+
+		select {
+		case <-cx.Done():
+			t.Fatal(err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	defer conn.Close()
+
+	// Shorter than the longest octet count. Emitted without waiting for more.
+	if _, err := conn.Write([]byte("3 <1>")); err != nil {
+		t.Fatal(err)
+	}
+	for len(w.lines()) == 0 {
+		select {
+		case <-cx.Done():
+			t.Fatal("timeout")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if got, wants := w.lines(), []string{`{"facility":0,"severity":1,"message":""}`}; !slices.Equal(wants, got) {
+		t.Fatalf("%#v != %#v", wants, got)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
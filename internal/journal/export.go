@@ -0,0 +1,127 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"time"
+)
+
+// https://systemd.io/JOURNAL_EXPORT_FORMATS/
+
+const ContentTypeExport = "application/vnd.fdo.journal"
+
+// Upper bound of a binary field, against broken or malicious senders.
+var maxExportFieldSize uint64 = 64 << 20
+
+func (e *Entry) setExportField(name string, value []byte) error {
+	switch name {
+	case "__CURSOR":
+		e.Cursor = string(value)
+
+	case "__REALTIME_TIMESTAMP":
+		usec, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return err
+		}
+		e.Realtime = time.UnixMicro(usec)
+
+	case "__MONOTONIC_TIMESTAMP":
+		usec, err := strconv.ParseUint(string(value), 10, 64)
+		if err != nil {
+			return err
+		}
+		e.Monotonic = usec
+
+	case "_BOOT_ID":
+		id, err := hex.DecodeString(string(value))
+		if err != nil || len(id) != len(e.BootId) {
+			return fmt.Errorf("invalid _BOOT_ID: %q", value)
+		}
+		copy(e.BootId[:], id)
+
+	default:
+		e.Fields = append(e.Fields, Field{Name: name, Value: value})
+	}
+
+	return nil
+}
+
+// Read entries in the Journal Export Format.
+func ReadExport(r io.Reader) iter.Seq2[*Entry, error] {
+	return func(yield func(*Entry, error) bool) {
+		br := bufio.NewReader(r)
+
+		var e *Entry
+		for {
+			line, err := br.ReadBytes('\n')
+			if err != nil {
+				if errors.Is(err, io.EOF) && len(line) == 0 {
+					if e != nil {
+						yield(e, nil)
+					}
+					return
+				}
+				if errors.Is(err, io.EOF) {
+					err = io.ErrUnexpectedEOF
+				}
+				yield(nil, err)
+				return
+			}
+			line = line[:len(line)-1]
+
+			// Entries are separated by an empty line.
+			if len(line) == 0 {
+				if e != nil {
+					if !yield(e, nil) {
+						return
+					}
+					e = nil
+				}
+				continue
+			}
+
+			if e == nil {
+				e = new(Entry)
+			}
+
+			if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+				if err := e.setExportField(string(name), bytes.Clone(value)); err != nil {
+					yield(nil, err)
+					return
+				}
+				continue
+			}
+
+			// Binary field. Little-endian 64-bit size, data and a newline.
+			var size uint64
+			if err := binary.Read(br, binary.LittleEndian, &size); err != nil {
+				yield(nil, err)
+				return
+			}
+			if size > maxExportFieldSize {
+				yield(nil, fmt.Errorf("field %s too large: %d", line, size))
+				return
+			}
+			value := make([]byte, size+1)
+			if _, err := io.ReadFull(br, value); err != nil {
+				yield(nil, err)
+				return
+			}
+			if value[size] != '\n' {
+				yield(nil, fmt.Errorf("field %s: missing newline", line))
+				return
+			}
+			if err := e.setExportField(string(line), value[:size]); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
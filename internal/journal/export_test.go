@@ -0,0 +1,56 @@
+package journal_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/journal"
+)
+
+func binaryField(name string, value []byte) []byte {
+	b := []byte(name + "\n")
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+func TestReadExport(t *testing.T) {
+	data := bytes.Join([][]byte{
+		[]byte("__CURSOR=s=01;i=1\n__REALTIME_TIMESTAMP=1704067200000000\n__MONOTONIC_TIMESTAMP=1000\n_BOOT_ID=000102030405060708090a0b0c0d0e0f\nMESSAGE=hello\n"),
+		binaryField("BIN", []byte("a\nb=\xff")),
+		[]byte("\n"),
+		[]byte("MESSAGE=second\n"),
+	}, nil)
+
+	entries := []*journal.Entry{}
+	for e, err := range journal.ReadExport(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d != 2", len(entries))
+	}
+
+	b, err := entries[0].MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wants := `{"__CURSOR":"s=01;i=1","__REALTIME_TIMESTAMP":"1704067200000000","__MONOTONIC_TIMESTAMP":"1000","_BOOT_ID":"000102030405060708090a0b0c0d0e0f","MESSAGE":"hello","BIN":[97,10,98,61,255]}`
+	if string(b) != wants {
+		t.Fatalf("%s != %s", b, wants)
+	}
+
+	if v, ok := entries[1].Get("MESSAGE"); !ok || string(v) != "second" {
+		t.Fatalf("%q != second", v)
+	}
+
+	// Truncated binary field
+	for _, err := range journal.ReadExport(bytes.NewReader(binaryField("BIN", []byte("abc"))[:12])) {
+		if err == nil {
+			t.Fatal("must fail")
+		}
+	}
+}
@@ -0,0 +1,512 @@
+// Package journal reads systemd journal files.
+//
+// https://systemd.io/JOURNAL_FILE_FORMAT/
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/edsrzf/mmap-go"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+var ErrInvalid = errors.New("invalid journal file")
+
+const signature = "LPKSHHRH"
+
+const (
+	incompatibleCompressedXz   = 1 << 0
+	incompatibleCompressedLz4  = 1 << 1
+	incompatibleKeyedHash      = 1 << 2
+	incompatibleCompressedZstd = 1 << 3
+	incompatibleCompact        = 1 << 4
+
+	incompatibleSupported = incompatibleCompressedXz | incompatibleCompressedLz4 | incompatibleKeyedHash | incompatibleCompressedZstd | incompatibleCompact
+)
+
+const (
+	objectData       = 1
+	objectEntry      = 3
+	objectEntryArray = 6
+)
+
+const (
+	objectCompressedXz   = 1 << 0
+	objectCompressedLz4  = 1 << 1
+	objectCompressedZstd = 1 << 2
+)
+
+const (
+	headerMinSize    = 208
+	objectHeaderSize = 16
+)
+
+// Fixed part of each object type, followed by the items.
+func (f *File) objectMinSize(typ uint8) uint64 {
+	switch typ {
+	case objectData:
+		if f.compact {
+			return 72
+		}
+		return 64
+	case objectEntry:
+		return 64
+	case objectEntryArray:
+		return 24
+	}
+	return objectHeaderSize
+}
+
+var zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+	return zstd.NewReader(nil)
+})
+
+type File struct {
+	fp  *os.File
+	mem mmap.MMap
+
+	compact          bool
+	fileId           [16]byte
+	seqnumId         [16]byte
+	nEntries         uint64
+	entryArrayOffset uint64
+
+	// Read by EntryOffsets so far. Entries are added to the last entry array.
+	offsets   []uint64
+	tailArray uint64
+	tailItems uint64
+}
+
+// The file is kept open to follow it by Refresh, even if renamed.
+func Open(path string) (*File, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{fp: fp}
+	if err := f.mmap(); err != nil {
+		_ = fp.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := f.readHeader(); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+func (f *File) mmap() error {
+	fi, err := f.fp.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() < headerMinSize {
+		return ErrInvalid
+	}
+	if f.mem != nil && fi.Size() == int64(len(f.mem)) {
+		return nil
+	}
+
+	mem, err := mmap.Map(f.fp, mmap.RDONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to mmap: %w", err)
+	}
+	if f.mem != nil {
+		_ = f.mem.Unmap()
+	}
+	f.mem = mem
+	return nil
+}
+
+// Follow the entries appended by journald. The file is mapped again if grown.
+func (f *File) Refresh() error {
+	if err := f.mmap(); err != nil {
+		return err
+	}
+	return f.readHeader()
+}
+
+func (f *File) Close() error {
+	return errors.Join(f.mem.Unmap(), f.fp.Close())
+}
+
+func (f *File) readHeader() error {
+	h := []byte(f.mem)
+	if string(h[0:8]) != signature {
+		return ErrInvalid
+	}
+
+	incompatible := binary.LittleEndian.Uint32(h[12:])
+	if incompatible&^incompatibleSupported != 0 {
+		return fmt.Errorf("unsupported incompatible flags %#x", incompatible)
+	}
+	f.compact = incompatible&incompatibleCompact != 0
+
+	copy(f.fileId[:], h[24:40])
+	copy(f.seqnumId[:], h[72:88])
+	f.nEntries = binary.LittleEndian.Uint64(h[152:])
+	f.entryArrayOffset = binary.LittleEndian.Uint64(h[176:])
+	return nil
+}
+
+// Identifies the file across renames (e.g. archived by journald).
+func (f *File) FileId() [16]byte {
+	return f.fileId
+}
+
+func (f *File) object(offset uint64, typ uint8) ([]byte, error) {
+	size := uint64(len(f.mem))
+	if offset == 0 || offset%8 != 0 || offset > size-objectHeaderSize {
+		return nil, fmt.Errorf("object %d: %w", offset, ErrInvalid)
+	}
+
+	o := f.mem[offset:]
+	if o[0] != typ {
+		return nil, fmt.Errorf("object %d: unexpected type %d", offset, o[0])
+	}
+	osize := binary.LittleEndian.Uint64(o[8:])
+	if osize < f.objectMinSize(typ) || osize > uint64(len(o)) {
+		return nil, fmt.Errorf("object %d: %w", offset, ErrInvalid)
+	}
+
+	return o[:osize], nil
+}
+
+// Offsets of the entry objects, in the order of sequence number.
+// Only the entries added after the last call are read from the entry arrays. The result must not be modified.
+func (f *File) EntryOffsets() ([]uint64, error) {
+	itemSize := uint64(8)
+	if f.compact {
+		itemSize = 4
+	}
+
+	offset, skip := f.tailArray, f.tailItems
+	if offset == 0 {
+		offset = f.entryArrayOffset
+	}
+	// Guard against loops in corrupted files.
+	for n := 0; offset != 0 && uint64(len(f.offsets)) < f.nEntries && n < len(f.mem)/objectHeaderSize; n++ {
+		o, err := f.object(offset, objectEntryArray)
+		if err != nil {
+			return nil, err
+		}
+
+		items := o[24:]
+		i := skip * itemSize
+		for ; i+itemSize <= uint64(len(items)) && uint64(len(f.offsets)) < f.nEntries; i += itemSize {
+			var item uint64
+			if f.compact {
+				item = uint64(binary.LittleEndian.Uint32(items[i:]))
+			} else {
+				item = binary.LittleEndian.Uint64(items[i:])
+			}
+			if item == 0 {
+				// unused tail
+				break
+			}
+			f.offsets = append(f.offsets, item)
+		}
+		f.tailArray, f.tailItems = offset, i/itemSize
+
+		offset, skip = binary.LittleEndian.Uint64(o[16:]), 0
+	}
+
+	return f.offsets, nil
+}
+
+// Realtime of the entry, without reading data objects.
+func (f *File) Realtime(offset uint64) (time.Time, error) {
+	o, err := f.object(offset, objectEntry)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.UnixMicro(int64(binary.LittleEndian.Uint64(o[24:]))), nil
+}
+
+type Field struct {
+	Name  string
+	Value []byte
+}
+
+type Entry struct {
+	Cursor    string
+	SeqnumId  [16]byte
+	Seqnum    uint64
+	Realtime  time.Time
+	Monotonic uint64
+	BootId    [16]byte
+	Fields    []Field
+}
+
+// Position of an entry in a cursor.
+type Position struct {
+	SeqnumId [16]byte
+	Seqnum   uint64
+	Realtime time.Time
+}
+
+// Parses a cursor of journalctl. e.g. `s=...;i=...;b=...;m=...;t=...;x=...`
+func ParseCursor(cursor string) (*Position, error) {
+	p := new(Position)
+	found := 0
+	for item := range strings.SplitSeq(cursor, ";") {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid cursor: %s", cursor)
+		}
+
+		switch key {
+		case "s":
+			b, err := hex.DecodeString(value)
+			if err != nil || len(b) != len(p.SeqnumId) {
+				return nil, fmt.Errorf("invalid cursor: %s", cursor)
+			}
+			copy(p.SeqnumId[:], b)
+			found |= 1
+		case "i":
+			n, err := strconv.ParseUint(value, 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cursor: %s", cursor)
+			}
+			p.Seqnum = n
+			found |= 2
+		case "t":
+			n, err := strconv.ParseUint(value, 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cursor: %s", cursor)
+			}
+			p.Realtime = time.UnixMicro(int64(n))
+			found |= 4
+		}
+	}
+	if found != 7 {
+		return nil, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+
+	return p, nil
+}
+
+// Same as journalctl, by the sequence number if the same sequence, otherwise by the realtime.
+func (e *Entry) After(p *Position) bool {
+	if e.SeqnumId == p.SeqnumId {
+		return e.Seqnum > p.Seqnum
+	}
+	return e.Realtime.After(p.Realtime)
+}
+
+func (f *File) Entry(offset uint64) (*Entry, error) {
+	o, err := f.object(offset, objectEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	seqnum := binary.LittleEndian.Uint64(o[16:])
+	realtime := binary.LittleEndian.Uint64(o[24:])
+	monotonic := binary.LittleEndian.Uint64(o[32:])
+	xorHash := binary.LittleEndian.Uint64(o[56:])
+
+	e := &Entry{
+		SeqnumId:  f.seqnumId,
+		Seqnum:    seqnum,
+		Realtime:  time.UnixMicro(int64(realtime)),
+		Monotonic: monotonic,
+	}
+	copy(e.BootId[:], o[40:56])
+	// Same format as journalctl.
+	e.Cursor = fmt.Sprintf("s=%s;i=%x;b=%s;m=%x;t=%x;x=%x",
+		hex.EncodeToString(f.seqnumId[:]), seqnum, hex.EncodeToString(e.BootId[:]), monotonic, realtime, xorHash)
+
+	itemSize := 16
+	if f.compact {
+		itemSize = 4
+	}
+
+	items := o[64:]
+	for i := 0; i+itemSize <= len(items); i += itemSize {
+		var item uint64
+		if f.compact {
+			item = uint64(binary.LittleEndian.Uint32(items[i:]))
+		} else {
+			item = binary.LittleEndian.Uint64(items[i:])
+		}
+
+		payload, err := f.data(item)
+		if err != nil {
+			return nil, err
+		}
+
+		name, value, ok := bytes.Cut(payload, []byte("="))
+		if !ok {
+			continue
+		}
+		// Valid after the file is mapped again.
+		e.Fields = append(e.Fields, Field{Name: string(name), Value: bytes.Clone(value)})
+	}
+
+	return e, nil
+}
+
+func (f *File) data(offset uint64) ([]byte, error) {
+	o, err := f.object(offset, objectData)
+	if err != nil {
+		return nil, err
+	}
+
+	start := 64
+	if f.compact {
+		start = 72
+	}
+	payload := o[start:]
+
+	switch flags := o[1]; {
+	case flags&objectCompressedXz != 0:
+		r, err := xz.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+
+	case flags&objectCompressedLz4 != 0:
+		// 64-bit little-endian uncompressed size, followed by a LZ4 block.
+		if len(payload) < 8 {
+			return nil, fmt.Errorf("data %d: %w", offset, ErrInvalid)
+		}
+		size := binary.LittleEndian.Uint64(payload)
+		if size > uint64(len(payload))*255 {
+			return nil, fmt.Errorf("data %d: %w", offset, ErrInvalid)
+		}
+		buf := make([]byte, size)
+		n, err := lz4.UncompressBlock(payload[8:], buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+
+	case flags&objectCompressedZstd != 0:
+		dec, err := zstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		return dec.DecodeAll(payload, nil)
+	}
+
+	return payload, nil
+}
+
+func (e *Entry) Get(name string) ([]byte, bool) {
+	for _, field := range e.Fields {
+		if field.Name == name {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+func fieldValue(value []byte) any {
+	if utf8.Valid(value) {
+		return string(value)
+	}
+
+	// Same as journalctl. Array of bytes.
+	result := make([]int, 0, len(value))
+	for _, b := range value {
+		result = append(result, int(b))
+	}
+	return result
+}
+
+func (e *Entry) values() ([]string, map[string][]any) {
+	names := make([]string, 0, len(e.Fields)+4)
+	values := make(map[string][]any)
+	// Entries received in the export format may lack them. Entries with a cursor have all.
+	meta := func(name string, ok bool, value string) {
+		if ok || e.Cursor != "" {
+			names = append(names, name)
+			values[name] = []any{value}
+		}
+	}
+	meta("__CURSOR", false, e.Cursor)
+	meta("__REALTIME_TIMESTAMP", !e.Realtime.IsZero(), strconv.FormatInt(e.Realtime.UnixMicro(), 10))
+	meta("__MONOTONIC_TIMESTAMP", e.Monotonic != 0, strconv.FormatUint(e.Monotonic, 10))
+	meta("_BOOT_ID", e.BootId != [16]byte{}, hex.EncodeToString(e.BootId[:]))
+	for _, field := range e.Fields {
+		if _, ok := values[field.Name]; !ok {
+			names = append(names, field.Name)
+		}
+		values[field.Name] = append(values[field.Name], fieldValue(field.Value))
+	}
+	return names, values
+}
+
+// Without the trailing newline.
+func encode(buf *bytes.Buffer, v any) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+func encodeValues(buf *bytes.Buffer, values []any) error {
+	if len(values) == 1 {
+		return encode(buf, values[0])
+	}
+	return encode(buf, values)
+}
+
+// Same values as `journalctl --output=json`, by the field name.
+func (e *Entry) JSONFields() (map[string]json.RawMessage, error) {
+	names, values := e.values()
+
+	result := make(map[string]json.RawMessage, len(names))
+	for _, name := range names {
+		buf := new(bytes.Buffer)
+		if err := encodeValues(buf, values[name]); err != nil {
+			return nil, err
+		}
+		result[name] = buf.Bytes()
+	}
+	return result, nil
+}
+
+// Same format as `journalctl --output=json`.
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	names, values := e.values()
+
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := encode(buf, name); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := encodeValues(buf, values[name]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
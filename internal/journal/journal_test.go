@@ -0,0 +1,165 @@
+package journal_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/journal"
+	"github.com/ysuzuki-bysystems/seigo/internal/journal/journaltest"
+)
+
+func TestEntry(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []journaltest.Entry{
+		{Realtime: base, Fields: []string{"MESSAGE=hello", "_SYSTEMD_UNIT=app.service"}},
+		{Realtime: base.Add(time.Second), Fields: []string{"MESSAGE=<world>", "TAG=a", "TAG=b", "BIN=\xff"}},
+	}
+
+	for _, compact := range []bool{false, true} {
+		for _, compression := range []string{"", "xz", "lz4", "zstd"} {
+			t.Run(fmt.Sprintf("compact=%v,compression=%s", compact, compression), func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "system.journal")
+				opts := &journaltest.Options{
+					FileId:      [16]byte{1},
+					Compact:     compact,
+					Compression: compression,
+				}
+				journaltest.Write(t, path, opts, entries)
+
+				f, err := journal.Open(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+
+				if f.FileId() != opts.FileId {
+					t.Fatalf("%x != %x", f.FileId(), opts.FileId)
+				}
+
+				offsets, err := f.EntryOffsets()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(offsets) != 2 {
+					t.Fatalf("%d != 2", len(offsets))
+				}
+
+				realtime, err := f.Realtime(offsets[1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !realtime.Equal(base.Add(time.Second)) {
+					t.Fatalf("%s != %s", realtime, base.Add(time.Second))
+				}
+
+				e, err := f.Entry(offsets[1])
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				names := []string{}
+				for _, field := range e.Fields {
+					names = append(names, fmt.Sprintf("%s=%s", field.Name, field.Value))
+				}
+				if wants := entries[1].Fields; !slices.Equal(wants, names) {
+					t.Fatalf("%#v != %#v", wants, names)
+				}
+
+				b, err := e.MarshalJSON()
+				if err != nil {
+					t.Fatal(err)
+				}
+				wants := `{"__CURSOR":"s=01010101010101010101010101010101;i=2;b=00000000000000000000000000000000;m=7d0;t=60dd710306240;x=0","__REALTIME_TIMESTAMP":"1704067201000000","__MONOTONIC_TIMESTAMP":"2000","_BOOT_ID":"00000000000000000000000000000000","MESSAGE":"<world>","TAG":["a","b"],"BIN":[255]}`
+				if string(b) != wants {
+					t.Fatalf("%s != %s", b, wants)
+				}
+			})
+		}
+	}
+}
+
+func TestCorrupted(t *testing.T) {
+	entries := []journaltest.Entry{
+		{Realtime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Fields: []string{"MESSAGE=hello"}},
+	}
+
+	// Truncates the object size to the object header.
+	corrupt := func(t *testing.T, offset func(f *journal.File, b []byte) uint64) *journal.File {
+		path := filepath.Join(t.TempDir(), "system.journal")
+		journaltest.Write(t, path, &journaltest.Options{}, entries)
+
+		f, err := journal.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o := offset(f, b)
+		_ = f.Close()
+
+		binary.LittleEndian.PutUint64(b[o+8:], 16)
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err = journal.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = f.Close() })
+		return f
+	}
+
+	t.Run("entry array", func(t *testing.T) {
+		f := corrupt(t, func(f *journal.File, b []byte) uint64 {
+			return binary.LittleEndian.Uint64(b[176:])
+		})
+		if _, err := f.EntryOffsets(); !errors.Is(err, journal.ErrInvalid) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("entry", func(t *testing.T) {
+		f := corrupt(t, func(f *journal.File, b []byte) uint64 {
+			offsets, err := f.EntryOffsets()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return offsets[0]
+		})
+		offsets, err := f.EntryOffsets()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Realtime(offsets[0]); !errors.Is(err, journal.ErrInvalid) {
+			t.Fatal(err)
+		}
+		if _, err := f.Entry(offsets[0]); !errors.Is(err, journal.ErrInvalid) {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestParseCursor(t *testing.T) {
+	p, err := journal.ParseCursor("s=000102030405060708090a0b0c0d0e0f;i=1f;b=00;m=0;t=5f;x=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.SeqnumId != [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15} || p.Seqnum != 0x1f || p.Realtime.UnixMicro() != 0x5f {
+		t.Fatalf("%#v", p)
+	}
+
+	for _, cursor := range []string{"", "s=00;i=1;t=1", "i=1;t=1", "s=000102030405060708090a0b0c0d0e0f;i=x;t=1"} {
+		if _, err := journal.ParseCursor(cursor); err == nil {
+			t.Fatalf("must fail: %s", cursor)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package journaltest writes minimal systemd journal files for tests.
+// Hash tables are not written.
+package journaltest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+const headerSize = 272
+
+type Entry struct {
+	Realtime time.Time
+	// `NAME=value`
+	Fields []string
+}
+
+type Options struct {
+	FileId  [16]byte
+	Compact bool
+	// "", "xz", "lz4" or "zstd"
+	Compression string
+}
+
+type writer struct {
+	t    testing.TB
+	buf  []byte
+	opts *Options
+}
+
+func (w *writer) object(typ, flags uint8, body []byte) uint64 {
+	offset := uint64(len(w.buf))
+
+	header := make([]byte, 16)
+	header[0] = typ
+	header[1] = flags
+	binary.LittleEndian.PutUint64(header[8:], uint64(16+len(body)))
+	w.buf = append(w.buf, header...)
+	w.buf = append(w.buf, body...)
+	for len(w.buf)%8 != 0 {
+		w.buf = append(w.buf, 0)
+	}
+
+	return offset
+}
+
+func (w *writer) compress(payload []byte) (uint8, []byte) {
+	switch w.opts.Compression {
+	case "xz":
+		buf := new(bytes.Buffer)
+		xw, err := xz.NewWriter(buf)
+		if err != nil {
+			w.t.Fatal(err)
+		}
+		if _, err := xw.Write(payload); err != nil {
+			w.t.Fatal(err)
+		}
+		if err := xw.Close(); err != nil {
+			w.t.Fatal(err)
+		}
+		return 1 << 0, buf.Bytes()
+
+	case "lz4":
+		dst := make([]byte, 8+lz4.CompressBlockBound(len(payload)))
+		binary.LittleEndian.PutUint64(dst, uint64(len(payload)))
+		n, err := lz4.CompressBlock(payload, dst[8:], nil)
+		if err != nil {
+			w.t.Fatal(err)
+		}
+		return 1 << 1, dst[:8+n]
+
+	case "zstd":
+		enc, err := zstd.NewWriter(nil, zstd.WithSingleSegment(true))
+		if err != nil {
+			w.t.Fatal(err)
+		}
+		defer enc.Close()
+		return 1 << 2, enc.EncodeAll(payload, nil)
+	}
+
+	return 0, payload
+}
+
+func (w *writer) data(payload string) uint64 {
+	flags, body := w.compress([]byte(payload))
+
+	size := 48
+	if w.opts.Compact {
+		size = 56
+	}
+	return w.object(1, flags, append(make([]byte, size), body...))
+}
+
+func (w *writer) entry(seqnum uint64, e *Entry) uint64 {
+	items := make([]uint64, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		items = append(items, w.data(field))
+	}
+
+	body := make([]byte, 48)
+	binary.LittleEndian.PutUint64(body[0:], seqnum)
+	binary.LittleEndian.PutUint64(body[8:], uint64(e.Realtime.UnixMicro()))
+	binary.LittleEndian.PutUint64(body[16:], seqnum*1000)
+	for _, item := range items {
+		if w.opts.Compact {
+			body = binary.LittleEndian.AppendUint32(body, uint32(item))
+		} else {
+			body = binary.LittleEndian.AppendUint64(body, item)
+			body = binary.LittleEndian.AppendUint64(body, 0)
+		}
+	}
+
+	return w.object(3, 0, body)
+}
+
+func (w *writer) entryArray(items []uint64) uint64 {
+	body := make([]byte, 8)
+	for _, item := range items {
+		if w.opts.Compact {
+			body = binary.LittleEndian.AppendUint32(body, uint32(item))
+		} else {
+			body = binary.LittleEndian.AppendUint64(body, item)
+		}
+	}
+	// unused tail
+	body = append(body, make([]byte, 16)...)
+
+	return w.object(6, 0, body)
+}
+
+func Write(t testing.TB, path string, opts *Options, entries []Entry) {
+	t.Helper()
+
+	w := &writer{
+		t:    t,
+		buf:  make([]byte, headerSize),
+		opts: opts,
+	}
+
+	offsets := make([]uint64, 0, len(entries))
+	for i := range entries {
+		offsets = append(offsets, w.entry(uint64(i+1), &entries[i]))
+	}
+	arrayOffset := uint64(0)
+	if len(offsets) > 0 {
+		arrayOffset = w.entryArray(offsets)
+	}
+
+	var incompatible uint32
+	if opts.Compact {
+		incompatible |= 1 << 4
+	}
+	switch opts.Compression {
+	case "xz":
+		incompatible |= 1 << 0
+	case "lz4":
+		incompatible |= 1 << 1
+	case "zstd":
+		incompatible |= 1 << 3
+	}
+
+	h := w.buf[:headerSize]
+	copy(h, "LPKSHHRH")
+	binary.LittleEndian.PutUint32(h[12:], incompatible)
+	copy(h[24:], opts.FileId[:])
+	copy(h[72:], strings.Repeat("\x01", 16))
+	binary.LittleEndian.PutUint64(h[88:], headerSize)
+	binary.LittleEndian.PutUint64(h[96:], uint64(len(w.buf)-headerSize))
+	binary.LittleEndian.PutUint64(h[152:], uint64(len(entries)))
+	binary.LittleEndian.PutUint64(h[176:], arrayOffset)
+
+	if err := os.WriteFile(path, w.buf, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
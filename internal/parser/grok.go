@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// `%{NAME}`, `%{NAME:field}` or `%{NAME:field:type}`
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([^:{}]+))?(?::(int|float|string))?\}`)
+
+// Against recursive patterns.
+const maxGrokDepth = 32
+
+type capture struct {
+	field string
+	// `int`, `float` or `string`
+	typ string
+}
+
+// Regular expression with named captures. Unmatched captures are omitted.
+type regexParser struct {
+	re *regexp.Regexp
+	// By submatch index.
+	captures []*capture
+}
+
+func newRegexParser(pattern string) (*regexParser, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	captures := make([]*capture, re.NumSubexp()+1)
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			captures[i] = &capture{field: name, typ: "string"}
+		}
+	}
+
+	return &regexParser{re: re, captures: captures}, nil
+}
+
+// Expand grok references into a regular expression.
+func newGrokParser(pattern string, custom map[string]string) (*regexParser, error) {
+	captures := make(map[string]*capture)
+
+	var expand func(pattern string, depth int) (string, error)
+	expand = func(pattern string, depth int) (string, error) {
+		if depth > maxGrokDepth {
+			return "", fmt.Errorf("grok: too deep: %s", pattern)
+		}
+
+		var err error
+		result := grokReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+			if err != nil {
+				return ""
+			}
+
+			m := grokReference.FindStringSubmatch(ref)
+			name, field, typ := m[1], m[2], m[3]
+
+			def, ok := custom[name]
+			if !ok {
+				def, ok = grokPatterns[name]
+			}
+			if !ok {
+				err = fmt.Errorf("grok: unknown pattern: %s", name)
+				return ""
+			}
+
+			var inner string
+			inner, err = expand(def, depth+1)
+			if err != nil {
+				return ""
+			}
+
+			if field == "" {
+				return fmt.Sprintf("(?:%s)", inner)
+			}
+
+			if typ == "" {
+				typ = "string"
+			}
+			// Field names are not always valid as a group name. e.g. `http.status`
+			group := fmt.Sprintf("_grok%d", len(captures))
+			captures[group] = &capture{field: field, typ: typ}
+			return fmt.Sprintf("(?P<%s>%s)", group, inner)
+		})
+		return result, err
+	}
+
+	expanded, err := expand(pattern, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("grok: %w", err)
+	}
+
+	// Groups written in the patterns are numbered too.
+	indexed := make([]*capture, re.NumSubexp()+1)
+	for i, name := range re.SubexpNames() {
+		indexed[i] = captures[name]
+	}
+
+	return &regexParser{re: re, captures: indexed}, nil
+}
+
+func (c *capture) value(text string) any {
+	switch c.typ {
+	case "int":
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+
+	return text
+}
+
+func (p *regexParser) Parse(line []byte) (json.RawMessage, bool) {
+	m := p.re.FindSubmatchIndex(line)
+	if m == nil {
+		return nil, false
+	}
+
+	obj := newObject()
+	for i, c := range p.captures {
+		if c == nil || m[2*i] < 0 {
+			continue
+		}
+
+		obj.set(c.field, c.value(string(line[m[2*i]:m[2*i+1]])))
+	}
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, false
+	}
+	return raw, true
+}
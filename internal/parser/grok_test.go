@@ -0,0 +1,76 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+)
+
+func TestGrok(t *testing.T) {
+	cfg := &parser.Config{
+		Parser:  "grok",
+		Pattern: `^%{TIMESTAMP_ISO8601:time} \[%{APPID:app.id}\] %{LOGLEVEL:level} took=%{NUMBER:took:float}ms %{GREEDYDATA:msg}`,
+		Patterns: map[string]string{
+			"APPID": `[a-z]+-%{INT}`,
+		},
+	}
+	testParse(t, cfg, []parseCase{
+		{
+			line:  `2024-01-01T00:00:00Z [api-12] WARN took=1.5ms slow query`,
+			wants: `{"time":"2024-01-01T00:00:00Z","app.id":"api-12","level":"WARN","took":1.5,"msg":"slow query"}`,
+		},
+		{line: `hello`},
+	})
+}
+
+func TestRegex(t *testing.T) {
+	cfg := &parser.Config{
+		Parser:  "regex",
+		Pattern: `^(?P<level>[A-Z]+)(?: (?P<code>[0-9]+))?: (?P<msg>.*)$`,
+	}
+	testParse(t, cfg, []parseCase{
+		{line: `ERROR 42: broken`, wants: `{"level":"ERROR","code":"42","msg":"broken"}`},
+		{line: `INFO: ok`, wants: `{"level":"INFO","msg":"ok"}`},
+		{line: `info: ok`},
+	})
+}
+
+func TestFormats(t *testing.T) {
+	testParse(t, &parser.Config{Parser: "nginx"}, []parseCase{
+		{
+			line:  `192.0.2.1 - - [01/Jan/2024:09:00:00 +0900] "GET /index.html?q=1 HTTP/1.1" 200 612 "-" "curl/8.5.0"`,
+			wants: `{"clientip":"192.0.2.1","ident":"-","auth":"-","timestamp":"01/Jan/2024:09:00:00 +0900","verb":"GET","request":"/index.html?q=1","httpversion":"1.1","response":200,"bytes":612,"referrer":"-","agent":"curl/8.5.0"}`,
+		},
+		{line: `{"msg":"json"}`},
+	})
+
+	testParse(t, &parser.Config{Parser: "apache"}, []parseCase{
+		{
+			line:  `2001:db8::1 - frank [10/Oct/2000:13:55:36 -0700] "-" 408 -`,
+			wants: `{"clientip":"2001:db8::1","ident":"-","auth":"frank","timestamp":"10/Oct/2000:13:55:36 -0700","rawrequest":"-","response":408}`,
+		},
+	})
+
+	testParse(t, &parser.Config{Parser: "haproxy"}, []parseCase{
+		{
+			line: `Feb  6 12:14:14 localhost haproxy[14389]: 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {1wt.eu} {} "GET /index.html HTTP/1.1"`,
+			wants: `{"syslog_timestamp":"Feb  6 12:14:14","syslog_server":"localhost","program":"haproxy","pid":14389,` +
+				`"client_ip":"10.0.1.2","client_port":33317,"timestamp":"06/Feb/2009:12:14:14.655","frontend_name":"http-in","backend_name":"static","server_name":"srv1",` +
+				`"time_request":10,"time_queue":0,"time_backend_connect":30,"time_backend_response":69,"time_duration":109,` +
+				`"http_status_code":200,"bytes_read":2750,"captured_request_cookie":"-","captured_response_cookie":"-","termination_state":"----",` +
+				`"actconn":1,"feconn":1,"beconn":1,"srvconn":1,"retries":0,"srv_queue":0,"backend_queue":0,` +
+				`"captured_request_headers":"1wt.eu","captured_response_headers":"","http_request":"GET /index.html HTTP/1.1"}`,
+		},
+	})
+
+	testParse(t, &parser.Config{Parser: "postgres"}, []parseCase{
+		{
+			line:  `2024-01-01 00:00:00.123 UTC [1234] LOG:  database system is ready to accept connections`,
+			wants: `{"timestamp":"2024-01-01 00:00:00.123 UTC","pid":1234,"level":"LOG","message":"database system is ready to accept connections"}`,
+		},
+		{
+			line:  `2024-01-01 09:00:00.123 +09 [1234] app@shop ERROR:  relation "x" does not exist`,
+			wants: `{"timestamp":"2024-01-01 09:00:00.123 +09","pid":1234,"user":"app","database":"shop","level":"ERROR","message":"relation \"x\" does not exist"}`,
+		},
+	})
+}
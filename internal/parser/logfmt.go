@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// https://brandur.org/logfmt
+// e.g. `level=info msg="hello world" verbose`
+type logfmtParser struct{}
+
+func isLogfmtKeyChar(c byte) bool {
+	return c > ' ' && c != '=' && c != '"' && c != 0x7f
+}
+
+// Read a double quoted string at the head of s. Returns the unquoted value and the rest.
+func readQuoted(s string) (string, string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+
+		case '"':
+			value, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				// Not a Go string literal. e.g. `\'`
+				value = strings.ReplaceAll(s[1:i], `\"`, `"`)
+			}
+			return value, s[i+1:], true
+		}
+	}
+
+	return "", "", false
+}
+
+func (logfmtParser) Parse(line []byte) (json.RawMessage, bool) {
+	obj := newObject()
+	// Plain text is not logfmt even though every word is a bare key.
+	pairs := 0
+
+	s := string(line)
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		if s == "" {
+			break
+		}
+
+		i := 0
+		for i < len(s) && isLogfmtKeyChar(s[i]) {
+			i++
+		}
+		if i == 0 {
+			return nil, false
+		}
+		key := s[:i]
+		s = s[i:]
+
+		if !strings.HasPrefix(s, "=") {
+			// Bare key.
+			obj.set(key, true)
+			continue
+		}
+		s = s[1:]
+		pairs++
+
+		if strings.HasPrefix(s, `"`) {
+			value, rest, ok := readQuoted(s)
+			if !ok {
+				return nil, false
+			}
+			obj.set(key, value)
+			s = rest
+			continue
+		}
+
+		end := strings.IndexAny(s, " \t\r\n")
+		if end < 0 {
+			end = len(s)
+		}
+		obj.set(key, s[:end])
+		s = s[end:]
+	}
+
+	if pairs == 0 {
+		return nil, false
+	}
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, false
+	}
+	return raw, true
+}
+
+// Lenient key=value pairs separated by spaces, `,` or `;`. Other words are joined into `message`.
+// e.g. `Accepted user=alice, from="10.0.0.1"; port=22`
+type kvParser struct{}
+
+func isKvSeparator(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ',' || c == ';'
+}
+
+func splitKv(s string) []string {
+	tokens := make([]string, 0)
+
+	for {
+		for len(s) > 0 && isKvSeparator(s[0]) {
+			s = s[1:]
+		}
+		if s == "" {
+			return tokens
+		}
+
+		var quote byte
+		i := 0
+	token:
+		for ; i < len(s); i++ {
+			c := s[i]
+			switch {
+			case quote != 0 && c == '\\':
+				i++
+			case quote != 0 && c == quote:
+				quote = 0
+			case quote != 0:
+			case c == '"' || c == '\'':
+				quote = c
+			case isKvSeparator(c):
+				break token
+			}
+		}
+		if i > len(s) {
+			i = len(s)
+		}
+
+		tokens = append(tokens, s[:i])
+		s = s[i:]
+	}
+}
+
+func unquoteKv(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if v, _, ok := readQuoted(value); ok {
+			return v
+		}
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func (kvParser) Parse(line []byte) (json.RawMessage, bool) {
+	obj := newObject()
+	words := make([]string, 0)
+
+	for _, token := range splitKv(string(line)) {
+		key, value, ok := strings.Cut(token, "=")
+		if !ok || key == "" || strings.ContainsAny(key, `"'`) {
+			words = append(words, token)
+			continue
+		}
+
+		obj.set(key, unquoteKv(value))
+	}
+
+	if len(obj.keys) == 0 {
+		return nil, false
+	}
+	if len(words) > 0 {
+		obj.set("message", strings.Join(words, " "))
+	}
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, false
+	}
+	return raw, true
+}
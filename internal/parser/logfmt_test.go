@@ -0,0 +1,31 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+)
+
+func TestLogfmt(t *testing.T) {
+	testParse(t, &parser.Config{Parser: "logfmt"}, []parseCase{
+		{
+			line:  `time=2024-01-01T00:00:00Z level=info msg="hello \"world\"" verbose`,
+			wants: `{"time":"2024-01-01T00:00:00Z","level":"info","msg":"hello \"world\"","verbose":true}`,
+		},
+		{line: `a=1 a=2 b=`, wants: `{"a":"2","b":""}`},
+		{line: `msg="unterminated`},
+		{line: `  `},
+		{line: `plain text`},
+		{line: `"quoted"=1`},
+	})
+}
+
+func TestKv(t *testing.T) {
+	testParse(t, &parser.Config{Parser: "kv"}, []parseCase{
+		{
+			line:  `Accepted publickey user=alice, from="10.0.0.1 (lan)"; port='22'`,
+			wants: `{"user":"alice","from":"10.0.0.1 (lan)","port":"22","message":"Accepted publickey"}`,
+		},
+		{line: `no pairs here`},
+	})
+}
@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+type Config struct {
+	// `json` (default), `logfmt`, `kv`, `grok`, `regex`,
+	// or a built-in access log format: `nginx`, `apache`, `haproxy`, `postgres`
+	Parser string `json:"parser"`
+	// Pattern for `grok` or `regex`.
+	Pattern string `json:"pattern"`
+	// Additional grok patterns. e.g. `{ "APPID" = "[a-z]+-[0-9]+" }`
+	Patterns map[string]string `json:"patterns"`
+	// What to do with lines the parser cannot convert. `drop` (default), `wrap` or `error`
+	NonJson string `json:"non-json"`
+}
+
+// Converts a line into a JSON value.
+type Parser interface {
+	// Returns false if the line is not in the format.
+	Parse(line []byte) (json.RawMessage, bool)
+}
+
+// Built-in grok patterns by parser name.
+var formats = map[string]string{
+	"nginx":    "^%{NGINXACCESS}",
+	"apache":   "^%{APACHEACCESS}",
+	"haproxy":  "^%{HAPROXYHTTP}",
+	"postgres": "^%{POSTGRESQL}",
+}
+
+func New(cfg *Config) (Parser, error) {
+	switch cfg.Parser {
+	case "", "json":
+		return jsonParser{}, nil
+
+	case "logfmt":
+		return logfmtParser{}, nil
+
+	case "kv":
+		return kvParser{}, nil
+
+	case "grok":
+		if cfg.Pattern == "" {
+			return nil, fmt.Errorf("empty pattern")
+		}
+		return newGrokParser(cfg.Pattern, cfg.Patterns)
+
+	case "regex":
+		if cfg.Pattern == "" {
+			return nil, fmt.Errorf("empty pattern")
+		}
+		return newRegexParser(cfg.Pattern)
+	}
+
+	if pattern, ok := formats[cfg.Parser]; ok {
+		return newGrokParser(pattern, cfg.Patterns)
+	}
+
+	return nil, fmt.Errorf("unknown parser: %s", cfg.Parser)
+}
+
+var ErrNonJson = errors.New("non-JSON line")
+
+// Parser with the non-json policy.
+type Converter struct {
+	parser  Parser
+	nonJson string
+}
+
+func NewConverter(cfg *Config) (*Converter, error) {
+	switch cfg.NonJson {
+	case "", "drop", "wrap", "error":
+	default:
+		return nil, fmt.Errorf("unknown non-json: %s", cfg.NonJson)
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Converter{parser: p, nonJson: cfg.NonJson}, nil
+}
+
+// Returns nil if the line is dropped.
+func (c *Converter) Convert(line []byte) (json.RawMessage, error) {
+	if raw, ok := c.parser.Parse(line); ok {
+		return raw, nil
+	}
+
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil, nil
+	}
+
+	switch c.nonJson {
+	case "wrap":
+		obj := newObject()
+		obj.set("message", string(line))
+		obj.set("_raw", true)
+		return json.Marshal(obj)
+
+	case "error":
+		if len(line) > 256 {
+			line = line[:256]
+		}
+		return nil, fmt.Errorf("%w: %q", ErrNonJson, line)
+	}
+
+	return nil, nil
+}
+
+type jsonParser struct{}
+
+func (jsonParser) Parse(line []byte) (json.RawMessage, bool) {
+	var raw json.RawMessage
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return nil, false
+	}
+
+	return raw, true
+}
+
+// JSON object which keeps the order of the keys. The last value wins on duplicated keys.
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+func newObject() *object {
+	return &object{values: make(map[string]any)}
+}
+
+func (o *object) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
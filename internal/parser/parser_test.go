@@ -0,0 +1,116 @@
+package parser_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/parser"
+)
+
+type parseCase struct {
+	line  string
+	wants string
+}
+
+func testParse(t *testing.T, cfg *parser.Config, cases []parseCase) {
+	t.Helper()
+
+	p, err := parser.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		raw, ok := p.Parse([]byte(c.line))
+		if c.wants == "" {
+			if ok {
+				t.Fatalf("%s: must not be parsed: %s", c.line, raw)
+			}
+			continue
+		}
+
+		if !ok {
+			t.Fatalf("%s: not parsed", c.line)
+		}
+		if string(raw) != c.wants {
+			t.Fatalf("%s: %s != %s", c.line, c.wants, raw)
+		}
+	}
+}
+
+func TestJson(t *testing.T) {
+	testParse(t, &parser.Config{}, []parseCase{
+		{line: `{"msg":"hello"}`, wants: `{"msg":"hello"}`},
+		{line: `level=info`},
+	})
+}
+
+func TestNew(t *testing.T) {
+	cases := []*parser.Config{
+		{Parser: "unknown"},
+		{Parser: "grok"},
+		{Parser: "grok", Pattern: "%{UNKNOWN:x}"},
+		{Parser: "grok", Pattern: "%{A}", Patterns: map[string]string{"A": "%{A}"}},
+		{Parser: "regex", Pattern: "(?P<x"},
+	}
+
+	for _, cfg := range cases {
+		if _, err := parser.New(cfg); err == nil {
+			t.Fatalf("must fail: %#v", cfg)
+		}
+	}
+}
+
+func TestConverter(t *testing.T) {
+	lines := []string{
+		`{"msg":"hello"}`,
+		`panic: runtime error`,
+		``,
+	}
+
+	cases := []struct {
+		nonJson string
+		wants   []string
+		err     bool
+	}{
+		{nonJson: "", wants: []string{`{"msg":"hello"}`}},
+		{nonJson: "drop", wants: []string{`{"msg":"hello"}`}},
+		{nonJson: "wrap", wants: []string{`{"msg":"hello"}`, `{"message":"panic: runtime error","_raw":true}`}},
+		{nonJson: "error", wants: []string{`{"msg":"hello"}`}, err: true},
+	}
+
+	for _, c := range cases {
+		conv, err := parser.NewConverter(&parser.Config{NonJson: c.nonJson})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recv := []string{}
+		failed := false
+		for _, line := range lines {
+			raw, err := conv.Convert([]byte(line))
+			if err != nil {
+				if !errors.Is(err, parser.ErrNonJson) {
+					t.Fatal(err)
+				}
+				failed = true
+				break
+			}
+			if raw != nil {
+				recv = append(recv, string(raw))
+			}
+		}
+
+		if !slices.Equal(c.wants, recv) {
+			t.Fatalf("%s: %#v != %#v", c.nonJson, c.wants, recv)
+		}
+		if failed != c.err {
+			t.Fatalf("%s: unexpected error: %v", c.nonJson, failed)
+		}
+	}
+
+	if _, err := parser.NewConverter(&parser.Config{NonJson: "keep"}); err == nil {
+		t.Fatal("must fail")
+	}
+}
@@ -0,0 +1,76 @@
+package parser
+
+// Built-in grok patterns. Derived from the Logstash ones, rewritten for RE2.
+// https://github.com/logstash-plugins/logstash-patterns-core
+var grokPatterns = map[string]string{
+	"USERNAME":     `[a-zA-Z0-9._-]+`,
+	"USER":         `%{USERNAME}`,
+	"INT":          `[+-]?[0-9]+`,
+	"NUMBER":       `[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+)`,
+	"POSINT":       `\b[1-9][0-9]*\b`,
+	"NONNEGINT":    `\b[0-9]+\b`,
+	"WORD":         `\b\w+\b`,
+	"NOTSPACE":     `\S+`,
+	"SPACE":        `\s*`,
+	"DATA":         `.*?`,
+	"GREEDYDATA":   `.*`,
+	"QUOTEDSTRING": `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"QS":           `%{QUOTEDSTRING}`,
+	"UUID":         `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+
+	"IPV4":     `(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]{1,2})\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]{1,2})`,
+	"IPV6":     `(?:[0-9A-Fa-f]{0,4}:){2,7}(?:[0-9A-Fa-f]{0,4}|%{IPV4})(?:%[0-9A-Za-z]+)?`,
+	"IP":       `%{IPV6}|%{IPV4}`,
+	"HOSTNAME": `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST": `%{IP}|%{HOSTNAME}`,
+	"HOSTPORT": `%{IPORHOST}:%{POSINT}`,
+
+	"PATH":         `(?:/[^\s?#]*)+`,
+	"URIPROTO":     `[A-Za-z][A-Za-z0-9+.-]*`,
+	"URIHOST":      `%{IPORHOST}(?::%{POSINT})?`,
+	"URIPATH":      `/[^\s?#]*`,
+	"URIPARAM":     `\?[^\s#]*`,
+	"URIPATHPARAM": `%{URIPATH}(?:%{URIPARAM})?`,
+	"URI":          `%{URIPROTO}://(?:%{USER}(?::[^@]*)?@)?%{URIHOST}(?:%{URIPATHPARAM})?`,
+
+	"MONTH":             `\b(?:[Jj]an(?:uary)?|[Ff]eb(?:ruary)?|[Mm]ar(?:ch)?|[Aa]pr(?:il)?|[Mm]ay|[Jj]une?|[Jj]uly?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo]ct(?:ober)?|[Nn]ov(?:ember)?|[Dd]ec(?:ember)?)\b`,
+	"MONTHNUM":          `0?[1-9]|1[0-2]`,
+	"MONTHDAY":          `0[1-9]|[12][0-9]|3[01]|[1-9]`,
+	"DAY":               `\b(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)\b`,
+	"YEAR":              `[0-9]{4}`,
+	"HOUR":              `2[0-3]|[01]?[0-9]`,
+	"MINUTE":            `[0-5][0-9]`,
+	"SECOND":            `(?:[0-5]?[0-9]|60)(?:[.,][0-9]+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}:%{SECOND}`,
+	"ISO8601_TIMEZONE":  `Z|[+-]%{HOUR}(?::?%{MINUTE})?`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"TZ":                `[A-Z]{2,5}|[+-][0-9]{2}(?::?[0-9]{2})?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"PROG":              `[\x21-\x5a\x5c\x5e-\x7e]+`,
+	"SYSLOGPROG":        `%{PROG:program}(?:\[%{POSINT:pid:int}\])?`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert|panic)`,
+
+	// Apache / nginx. `LogFormat "%h %l %u %t \"%r\" %>s %b"`
+	"COMMONAPACHELOG": `%{IPORHOST:clientip} %{USER:ident} %{USER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{INT:response:int} (?:%{INT:bytes:int}|-)`,
+	// `LogFormat "%h %l %u %t \"%r\" %>s %b \"%{Referer}i\" \"%{User-agent}i\""`
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} "%{DATA:referrer}" "%{DATA:agent}"`,
+	// Either of the above.
+	"APACHEACCESS": `%{COMMONAPACHELOG}(?: "%{DATA:referrer}" "%{DATA:agent}")?`,
+	// `log_format combined`, optionally followed by `"$http_x_forwarded_for"`
+	"NGINXACCESS": `%{COMBINEDAPACHELOG}(?: "%{DATA:forwarded_for}")?`,
+
+	// HAProxy `option httplog`, optionally with the syslog header. The timestamp is in the local time of the host.
+	"HAPROXYDATE": `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME}`,
+	"HAPROXYHTTP": `(?:%{SYSLOGTIMESTAMP:syslog_timestamp} %{IPORHOST:syslog_server} %{SYSLOGPROG}: )?` +
+		`%{IP:client_ip}:%{INT:client_port:int} \[%{HAPROXYDATE:timestamp}\] %{NOTSPACE:frontend_name} %{NOTSPACE:backend_name}/%{NOTSPACE:server_name} ` +
+		`%{INT:time_request:int}/%{INT:time_queue:int}/%{INT:time_backend_connect:int}/%{INT:time_backend_response:int}/\+?%{INT:time_duration:int} ` +
+		`%{INT:http_status_code:int} \+?%{INT:bytes_read:int} %{NOTSPACE:captured_request_cookie} %{NOTSPACE:captured_response_cookie} %{NOTSPACE:termination_state} ` +
+		`%{INT:actconn:int}/%{INT:feconn:int}/%{INT:beconn:int}/%{INT:srvconn:int}/\+?%{INT:retries:int} %{INT:srv_queue:int}/%{INT:backend_queue:int} ` +
+		`(?:\{%{DATA:captured_request_headers}\} )?(?:\{%{DATA:captured_response_headers}\} )?"%{DATA:http_request}"`,
+
+	// PostgreSQL with `log_line_prefix = '%m [%p] '` (default) or `'%m [%p] %q%u@%d '`
+	// The timestamp includes the time zone of `log_timezone`. e.g. `2024-01-01 09:00:00.123 JST`
+	"PGTIMESTAMP": `%{TIMESTAMP_ISO8601}(?: %{TZ})?`,
+	"POSTGRESQL":  `%{PGTIMESTAMP:timestamp} \[%{POSINT:pid:int}\] (?:%{DATA:user}@%{DATA:database} )?%{WORD:level}: +%{GREEDYDATA:message}`,
+}
@@ -0,0 +1,50 @@
+package proc
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Resolve `./` or `../` relative program path from the config file directory.
+func ResolveBin(cfgPath, target string) string {
+	// relative path or not
+	if !strings.HasPrefix(target, ".") {
+		return target
+	}
+
+	dir := filepath.Dir(cfgPath)
+	return filepath.Join(dir, target)
+}
+
+// Command which is interrupted on context cancellation.
+func Command(cx context.Context, program string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(cx, program, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.Stdin = nil
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// Start the command and return its stdout.
+// Call `wait` after stdout is drained.
+func Start(cmd *exec.Cmd) (stdout io.Reader, wait func(), err error) {
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	wait = func() {
+		_, _ = cmd.Process.Wait()
+	}
+	return pipe, wait, nil
+}
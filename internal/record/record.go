@@ -0,0 +1,210 @@
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Looked up in order when no timestamp field is configured.
+var DefaultTimestampFields = []string{"time", "timestamp", "ts", "@timestamp"}
+
+// Timestamps without time zone are in the local time.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	// PostgreSQL `%m`. Zone abbreviations are in parseZoneAbbreviation.
+	"2006-01-02 15:04:05.999999999 -07",
+	"2006-01-02 15:04:05.999999999 -07:00",
+	// Access logs. e.g. Apache `%t`, HAProxy `accept_date`
+	"02/Jan/2006:15:04:05.999999999 -0700",
+	"02/Jan/2006:15:04:05.999999999",
+}
+
+// Offsets of zone abbreviations in minutes, after `timezone_abbreviations = 'Default'` of PostgreSQL.
+// time.Parse makes up zero offset for abbreviations other than the local zone.
+var zoneAbbreviations = map[string]int{
+	"UTC": 0, "GMT": 0, "WET": 0, "WEST": 60, "BST": 60,
+	"CET": 60, "CEST": 120, "EET": 120, "EEST": 180, "MSK": 180, "IST": 120,
+	"HKT": 480, "AWST": 480, "JST": 540, "KST": 540,
+	"ACST": 570, "ACDT": 630, "AEST": 600, "AEDT": 660, "NZST": 720, "NZDT": 780,
+	"HST": -600, "AKST": -540, "AKDT": -480, "PST": -480, "PDT": -420, "MST": -420, "MDT": -360,
+	"CST": -360, "CDT": -300, "EST": -300, "EDT": -240, "AST": -240, "ADT": -180, "NST": -210, "NDT": -150,
+}
+
+// e.g. `2024-01-01 09:00:00.123 JST`. Unknown abbreviations are in the local time, same as no time zone.
+func parseZoneAbbreviation(text string) (time.Time, bool) {
+	i := strings.LastIndexByte(text, ' ')
+	abbr := text[i+1:]
+	if i < 0 || abbr == "" || strings.IndexFunc(abbr, func(r rune) bool { return r < 'A' || r > 'Z' }) >= 0 {
+		return time.Time{}, false
+	}
+
+	loc := time.Local
+	if offset, ok := zoneAbbreviations[abbr]; ok {
+		loc = time.FixedZone(abbr, offset*60)
+	}
+
+	t, err := time.ParseInLocation("2006-01-02 15:04:05.999999999", text[:i], loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func parseUnix(v float64) time.Time {
+	// Guess the unit from its magnitude.
+	abs := math.Abs(v)
+	switch {
+	case abs >= 1e18:
+		return time.Unix(0, int64(v))
+	case abs >= 1e15:
+		return time.UnixMicro(int64(v))
+	case abs >= 1e12:
+		return time.UnixMilli(int64(v))
+	}
+
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+func parseTimestamp(raw json.RawMessage) (time.Time, bool) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		var num float64
+		if err := json.Unmarshal(raw, &num); err != nil {
+			return time.Time{}, false
+		}
+
+		return parseUnix(num), true
+	}
+
+	text = strings.TrimSpace(text)
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return t, true
+		}
+	}
+	if t, ok := parseZoneAbbreviation(text); ok {
+		return t, true
+	}
+
+	if num, err := strconv.ParseFloat(text, 64); err == nil {
+		return parseUnix(num), true
+	}
+
+	return time.Time{}, false
+}
+
+// Timestamp extracts the time of a JSON object record from the first of fields that holds a parsable value.
+// If fields is empty, DefaultTimestampFields is used.
+func Timestamp(raw json.RawMessage, fields ...string) (time.Time, bool) {
+	if len(fields) == 0 {
+		fields = DefaultTimestampFields
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return time.Time{}, false
+	}
+
+	for _, field := range fields {
+		val, ok := obj[field]
+		if !ok {
+			continue
+		}
+
+		if t, ok := parseTimestamp(val); ok {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Merge sets fields to a JSON object record. Existing members of the same keys are replaced in place, and the others are appended.
+// Records other than objects are returned as is.
+func Merge(raw json.RawMessage, fields map[string]any) (json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) < 2 || trimmed[0] != '{' || len(fields) == 0 {
+		return raw, nil
+	}
+
+	member := func(buf []byte, key string, value any) ([]byte, error) {
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, ok := value.(json.RawMessage)
+		if !ok {
+			if v, err = json.Marshal(value); err != nil {
+				return nil, err
+			}
+		}
+
+		if buf[len(buf)-1] != '{' {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, k...)
+		buf = append(buf, ':')
+		return append(buf, v...), nil
+	}
+
+	buf := make([]byte, 0, len(trimmed)+64)
+	buf = append(buf, '{')
+	done := make(map[string]bool, len(fields))
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		if v, ok := fields[key]; ok {
+			if done[key] {
+				// Duplicated in the record.
+				continue
+			}
+			done[key] = true
+			if buf, err = member(buf, key, v); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// As is.
+		if buf, err = member(buf, key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		if done[key] {
+			continue
+		}
+
+		var err error
+		if buf, err = member(buf, key, fields[key]); err != nil {
+			return nil, err
+		}
+	}
+	buf = append(buf, '}')
+
+	return buf, nil
+}
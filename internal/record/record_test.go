@@ -0,0 +1,120 @@
+package record_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/record"
+)
+
+func TestTimestamp(t *testing.T) {
+	wants := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		raw    string
+		fields []string
+	}{
+		{raw: `{"time":"2024-01-01T00:00:00Z"}`},
+		{raw: `{"time":"2024-01-01T09:00:00+09:00"}`},
+		{raw: `{"ts":1704067200}`},
+		{raw: `{"ts":1704067200000}`},
+		{raw: `{"@timestamp":"1704067200"}`},
+		{raw: `{"timestamp":"01/Jan/2024:09:00:00 +0900"}`},
+		{raw: `{"time":"broken","at":"2024-01-01T00:00:00Z"}`, fields: []string{"time", "at"}},
+		{raw: `{"timestamp":"2024-01-01 00:00:00.123 UTC"}`},
+		{raw: `{"timestamp":"2024-01-01 09:00:00.123 +09"}`},
+	}
+
+	for _, c := range cases {
+		got, ok := record.Timestamp(json.RawMessage(c.raw), c.fields...)
+		if !ok {
+			t.Fatalf("not found: %s", c.raw)
+		}
+		if !got.Truncate(time.Second).Equal(wants) {
+			t.Fatalf("%s: %s != %s", c.raw, got, wants)
+		}
+	}
+
+	if _, ok := record.Timestamp(json.RawMessage(`{"msg":"hello"}`)); ok {
+		t.Fatal("unexpected")
+	}
+}
+
+func TestTimestampLocal(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("JST", 9*60*60)
+	t.Cleanup(func() {
+		time.Local = local
+	})
+
+	// Without time zone. e.g. HAProxy
+	wants := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, raw := range []string{
+		`{"time":"2024-01-01 09:00:00"}`,
+		`{"time":"2024-01-01T09:00:00"}`,
+		`{"timestamp":"01/Jan/2024:09:00:00.000"}`,
+		`{"timestamp":"2024-01-01 09:00:00.000 JST"}`,
+	} {
+		got, ok := record.Timestamp(json.RawMessage(raw))
+		if !ok {
+			t.Fatalf("not found: %s", raw)
+		}
+		if !got.Equal(wants) {
+			t.Fatalf("%s: %s != %s", raw, got, wants)
+		}
+	}
+}
+
+func TestTimestampZoneAbbreviation(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() {
+		time.Local = local
+	})
+
+	// PostgreSQL `%m` on other than the local zone.
+	cases := []struct {
+		raw   string
+		wants time.Time
+	}{
+		{`{"timestamp":"2024-01-01 09:00:00.000 JST"}`, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{`{"timestamp":"2024-01-01 09:00:00.000 EST"}`, time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC)},
+		{`{"timestamp":"2024-01-01 09:00:00.000 ACST"}`, time.Date(2023, 12, 31, 23, 30, 0, 0, time.UTC)},
+		// Unknown. Same as no time zone.
+		{`{"timestamp":"2024-01-01 09:00:00.000 XYZ"}`, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		got, ok := record.Timestamp(json.RawMessage(c.raw))
+		if !ok {
+			t.Fatalf("not found: %s", c.raw)
+		}
+		if !got.Equal(c.wants) {
+			t.Fatalf("%s: %s != %s", c.raw, got, c.wants)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		raw   string
+		wants string
+	}{
+		{raw: `{"msg":"hello"}`, wants: `{"msg":"hello","a":1,"b":"x"}`},
+		{raw: ` { } `, wants: `{"a":1,"b":"x"}`},
+		{raw: `[1]`, wants: `[1]`},
+		// Replaced in place.
+		{raw: `{"b":"old","msg":"hello","b":"dup"}`, wants: `{"b":"x","msg":"hello","a":1}`},
+		{raw: `{"msg": {"b":"<nested>"}}`, wants: `{"msg":{"b":"<nested>"},"a":1,"b":"x"}`},
+	}
+
+	for _, c := range cases {
+		got, err := record.Merge(json.RawMessage(c.raw), map[string]any{"b": "x", "a": 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != c.wants {
+			t.Fatalf("%s != %s", got, c.wants)
+		}
+	}
+}
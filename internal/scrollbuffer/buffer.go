@@ -2,6 +2,7 @@ package scrollbuffer
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"io"
@@ -15,6 +16,8 @@ var ErrClosed = errors.New("closed")
 
 var ErrDiscarded = errors.New("discarded")
 
+var ErrInvalidOffset = errors.New("invalid offset")
+
 // Lock token
 type token struct {
 	cond *sync.Cond
@@ -64,6 +67,8 @@ type entry struct {
 	next *entry
 	// Number of Readers being read. (Writer: R / Reader: RW)
 	ref int
+	// Offset of data[0] from the beginning of written bytes. Fixed when it becomes the tail. (Writer: RW / Reader: R)
+	offset int64
 }
 
 // EOF marker
@@ -102,6 +107,9 @@ func (e *entry) waitForFree(token *token) {
 }
 
 type ScrollBuffer struct {
+	// Random. Distinguishes offsets of other buffers (e.g. before restart).
+	id string
+
 	// Unix-like systems can remove open files, but not Windows. It is necessary to retain the name of the file to remove.
 	fname string
 	mem   mmap.MMap
@@ -191,6 +199,7 @@ func New(dir string, entrySize, numOfEntries int) (*ScrollBuffer, error) {
 	}
 
 	buf := &ScrollBuffer{
+		id:    rand.Text(),
 		fname: fname,
 		mem:   mem,
 		cond:  sync.NewCond(&sync.Mutex{}),
@@ -274,6 +283,7 @@ func (s *ScrollBuffer) write(token *token, b []byte) (int, error) {
 		//     ^ Tail
 		if tail.next != nil {
 			tail.state = filled
+			tail.next.offset = tail.offset + int64(tail.pos)
 			s.tail = tail.next
 			continue
 		}
@@ -359,6 +369,10 @@ func (s *ScrollBuffer) shutdown(cx context.Context, token *token) error {
 	return nil
 }
 
+func (s *ScrollBuffer) Id() string {
+	return s.id
+}
+
 func (s *ScrollBuffer) Shutdown(cx context.Context) error {
 	token := lock(s.cond)
 	defer unlock(token)
@@ -415,6 +429,9 @@ type Reader struct {
 	entry    *entry
 	pos      int
 	canceled bool
+
+	// Offset of the next byte to read.
+	offset int64
 }
 
 func (s *ScrollBuffer) NewReader(follow bool) *Reader {
@@ -428,19 +445,62 @@ func (s *ScrollBuffer) NewReader(follow bool) *Reader {
 		entry = s.head
 	}
 
+	var offset int64
 	if entry != eofEntry {
 		entry.use(token)
+		offset = entry.offset
 	}
 
 	return &Reader{
 		follow: follow,
 
-		cond:  s.cond,
-		entry: entry,
-		pos:   0,
+		cond:   s.cond,
+		entry:  entry,
+		pos:    0,
+		offset: offset,
 	}
 }
 
+// Reader from offset, which was returned by `Reader.Offset`.
+// ErrDiscarded if it is already discarded, ErrInvalidOffset if it is not written yet.
+func (s *ScrollBuffer) NewReaderAt(offset int64, follow bool) (*Reader, error) {
+	token := lock(s.cond)
+	defer unlock(token)
+
+	if s.head != eofEntry && offset < s.head.offset {
+		return nil, ErrDiscarded
+	}
+
+	for e := s.head; e != eofEntry; e = e.next {
+		end := e.offset + int64(e.pos)
+		if offset > end || (offset == end && e.state == filled && e.next != eofEntry) {
+			if e.state != filled {
+				break
+			}
+			continue
+		}
+
+		r := &Reader{
+			follow: follow,
+
+			cond:   s.cond,
+			entry:  e,
+			pos:    int(offset - e.offset),
+			offset: offset,
+		}
+		if offset == end && e.next == eofEntry {
+			// Already closed. At EOF.
+			r.entry = eofEntry
+			r.pos = 0
+		} else {
+			e.use(token)
+		}
+		return r, nil
+	}
+
+	return nil, ErrInvalidOffset
+}
+
 func (r *Reader) Read(b []byte) (int, error) {
 	token := lock(r.cond)
 	defer unlock(token)
@@ -462,6 +522,7 @@ func (r *Reader) Read(b []byte) (int, error) {
 		if len(src) > 0 {
 			n := copy(b, src)
 			r.pos += n
+			r.offset += int64(n)
 			return n, nil
 		}
 
@@ -485,6 +546,11 @@ func (r *Reader) Read(b []byte) (int, error) {
 	}
 }
 
+// Offset of the next byte to read. Must not be called concurrently with Read.
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
 func (r *Reader) Close() error {
 	if r.entry == eofEntry {
 		return nil // Already reached EOF.
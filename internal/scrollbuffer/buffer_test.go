@@ -281,3 +281,69 @@ func TestScrollBufferParallelWrite(t *testing.T) {
 		}
 	}
 }
+
+func TestScrollBufferReaderAt(t *testing.T) {
+	buf, err := scrollbuffer.New(t.TempDir(), 4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Shutdown(context.Background())
+
+	w := buf.NewWriter()
+	if _, err := w.Write([]byte("Hello, World!")); err != nil {
+		t.Fatal(err)
+	}
+
+	r := buf.NewReader(false)
+	b := make([]byte, 7)
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+	offset := r.Offset()
+	r.Close()
+	// Started from "o, W".
+	if offset != 11 {
+		t.Fatalf("%d != 11", offset)
+	}
+
+	// "Hell" is discarded.
+	if _, err := buf.NewReaderAt(2, false); !errors.Is(err, scrollbuffer.ErrDiscarded) {
+		t.Fatal(err)
+	}
+	if _, err := buf.NewReaderAt(14, false); !errors.Is(err, scrollbuffer.ErrInvalidOffset) {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		offset int64
+		wants  string
+	}{
+		{offset: offset, wants: "d!"},
+		{offset: 8, wants: "orld!"},
+		{offset: 13, wants: ""},
+	} {
+		r, err := buf.NewReaderAt(c.offset, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+		if string(b) != c.wants {
+			t.Fatalf("%q != %q", b, c.wants)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err = buf.NewReaderAt(13, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(r); err != nil || len(b) != 0 {
+		t.Fatal(b, err)
+	}
+}
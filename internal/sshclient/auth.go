@@ -0,0 +1,239 @@
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// A secret from one of the value, the file, the environment variable and the command.
+func readSecret(cx context.Context, cfgPath string, value, file, env, command string) (string, bool, error) {
+	switch {
+	case value != "":
+		return value, true, nil
+
+	case file != "":
+		b, err := os.ReadFile(resolvePath(cfgPath, file))
+		if err != nil {
+			return "", false, err
+		}
+		return strings.TrimRight(string(b), "\r\n"), true, nil
+
+	case env != "":
+		v, ok := os.LookupEnv(env)
+		if !ok {
+			return "", false, fmt.Errorf("no environment variable: %s", env)
+		}
+		return v, true, nil
+
+	case command != "":
+		b, err := exec.CommandContext(cx, "sh", "-c", command).Output()
+		if err != nil {
+			return "", false, fmt.Errorf("%s: %w", command, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), true, nil
+
+	default:
+		return "", false, nil
+	}
+}
+
+// Reasons of the authentication failure. Reported with the error of the handshake.
+type authNotes []string
+
+func (n *authNotes) add(format string, a ...any) {
+	*n = append(*n, fmt.Sprintf(format, a...))
+}
+
+func (n *authNotes) wrap(err error) error {
+	if len(*n) == 0 || !strings.Contains(err.Error(), "unable to authenticate") {
+		return err
+	}
+	return fmt.Errorf("%w (%s)", err, strings.Join(*n, "; "))
+}
+
+func agentAuthMethod(cx context.Context, cfg *Config, notes *authNotes) func() ([]ssh.Signer, error) {
+	agentPath := cfg.IdentityAgent
+	if agentPath == "" {
+		if val, ok := os.LookupEnv("SSH_AUTH_SOCK"); ok {
+			agentPath = val
+		}
+	}
+
+	if agentPath == "" {
+		return nil
+	}
+
+	conn, err := net.Dial("unix", agentPath)
+	if err != nil {
+		notes.add("identity-agent: %s", err)
+		return nil
+	}
+
+	context.AfterFunc(cx, func() {
+		conn.Close()
+	})
+	agent := agent.NewClient(conn)
+	return func() ([]ssh.Signer, error) {
+		signers, err := agent.Signers()
+		if err != nil {
+			return nil, err
+		}
+		if len(signers) == 0 {
+			notes.add("identity-agent %s: no keys", agentPath)
+		}
+		return signers, nil
+	}
+}
+
+func parseIdentityFile(cx context.Context, cfgPath string, cfg *Config, data []byte) (ssh.Signer, error) {
+	key, err := ssh.ParsePrivateKey(data)
+	if err == nil {
+		return key, nil
+	}
+
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return nil, err
+	}
+
+	passphrase, ok, err := readSecret(cx, cfgPath, "", cfg.IdentityPassphraseFile, cfg.IdentityPassphraseEnv, cfg.IdentityPassphraseCommand)
+	if err != nil {
+		return nil, fmt.Errorf("passphrase: %w", err)
+	}
+	if !ok {
+		return nil, errors.New("encrypted, needs identity-passphrase-file, identity-passphrase-env or identity-passphrase-command")
+	}
+
+	return ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+}
+
+// `certificate-file`, or `<identity-file>-cert.pub` if exists.
+func certificate(cfgPath string, cfg *Config, key ssh.Signer) (ssh.Signer, error) {
+	path := cfg.CertificateFile
+	if path == "" {
+		path = resolvePath(cfgPath, cfg.IdentityFile) + "-cert.pub"
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+	} else {
+		path = resolvePath(cfgPath, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, err
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s: not a certificate", path)
+	}
+
+	return ssh.NewCertSigner(cert, key)
+}
+
+func identityFileAuthMethod(cx context.Context, cfgPath string, cfg *Config, notes *authNotes) func() ([]ssh.Signer, error) {
+	identity := cfg.IdentityFile
+
+	if identity == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(resolvePath(cfgPath, identity))
+	if err != nil {
+		notes.add("identity-file: %s", err)
+		return nil
+	}
+
+	key, err := parseIdentityFile(cx, cfgPath, cfg, data)
+	if err != nil {
+		notes.add("identity-file %s: %s", identity, err)
+		return nil
+	}
+
+	signers := []ssh.Signer{key}
+	cert, err := certificate(cfgPath, cfg, key)
+	if err != nil {
+		notes.add("certificate-file: %s", err)
+	} else if cert != nil {
+		// Certificate first, same as OpenSSH.
+		signers = []ssh.Signer{cert, key}
+	}
+
+	return func() ([]ssh.Signer, error) {
+		return signers, nil
+	}
+}
+
+// Answer all questions with the password. e.g. PAM
+func keyboardInteractive(password string) ssh.KeyboardInteractiveChallenge {
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i := range questions {
+			answers[i] = password
+		}
+		return answers, nil
+	}
+}
+
+func authMethods(cx context.Context, cfgPath string, cfg *Config) ([]ssh.AuthMethod, *authNotes) {
+	notes := &authNotes{}
+	signersfns := make([]func() ([]ssh.Signer, error), 0)
+
+	if fn := agentAuthMethod(cx, cfg, notes); fn != nil {
+		signersfns = append(signersfns, fn)
+	}
+
+	if fn := identityFileAuthMethod(cx, cfgPath, cfg, notes); fn != nil {
+		signersfns = append(signersfns, fn)
+	}
+
+	publicKeyAuth := ssh.PublicKeysCallback(func() (signers []ssh.Signer, err error) {
+		results := make([]ssh.Signer, 0)
+		errs := make([]error, 0)
+
+		for _, fn := range signersfns {
+			r, err := fn()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			results = append(results, r...)
+		}
+
+		if len(results) > 0 {
+			return results, nil
+		}
+		if len(errs) == 1 {
+			return nil, errs[0]
+		}
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+
+		return []ssh.Signer{}, nil
+	})
+
+	methods := []ssh.AuthMethod{publicKeyAuth}
+
+	password, ok, err := readSecret(cx, cfgPath, cfg.Password, cfg.PasswordFile, cfg.PasswordEnv, cfg.PasswordCommand)
+	if err != nil {
+		notes.add("password: %s", err)
+	} else if ok {
+		methods = append(methods, ssh.Password(password), ssh.KeyboardInteractive(keyboardInteractive(password)))
+	}
+
+	return methods, notes
+}
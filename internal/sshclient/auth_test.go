@@ -0,0 +1,154 @@
+package sshclient_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient/sshtest"
+	"golang.org/x/crypto/ssh"
+)
+
+// An encrypted key and its certificate signed by a CA the server trusts.
+func writeCertifiedKey(t *testing.T, server *sshtest.Server, passphrase string) string {
+	t.Helper()
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetUserCA(ca.PublicKey())
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ssh.NewPublicKey(pubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &ssh.Certificate{
+		Key:             pub,
+		CertType:        ssh.UserCert,
+		KeyId:           "test",
+		ValidPrincipals: []string{"test"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+
+	pemData, err := ssh.MarshalPrivateKeyWithPassphrase(privKey, "", []byte(passphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ident := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(ident, pem.EncodeToMemory(pemData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ident+"-cert.pub", ssh.MarshalAuthorizedKey(cert), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return ident
+}
+
+func TestStartEncryptedCertifiedKey(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	t.Setenv("SEIGO_TEST_PASSPHRASE", "secret")
+
+	server := sshtest.NewServer(t)
+	ident := writeCertifiedKey(t, server, "secret")
+
+	passphraseFile := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(passphraseFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]func(cfg *sshclient.Config){
+		"file": func(cfg *sshclient.Config) {
+			cfg.IdentityPassphraseFile = passphraseFile
+		},
+		"env": func(cfg *sshclient.Config) {
+			cfg.IdentityPassphraseEnv = "SEIGO_TEST_PASSPHRASE"
+		},
+		"command": func(cfg *sshclient.Config) {
+			cfg.IdentityPassphraseCommand = "echo secret"
+		},
+	}
+	for name, fn := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := server.Config
+			cfg.IdentityFile = ident
+			fn(&cfg)
+
+			startAll(t, &cfg, 1)
+		})
+	}
+
+	t.Run("no passphrase", func(t *testing.T) {
+		cfg := server.Config
+		cfg.IdentityFile = ident
+
+		_, _, err := sshclient.Start(t.Context(), ".", &cfg, "true")
+		if err == nil {
+			t.Fatal("must fail")
+		}
+		if !strings.Contains(err.Error(), "identity-passphrase-file") {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestStartPassword(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	server := sshtest.NewServer(t)
+	server.SetPassword("secret")
+
+	cfg := server.Config
+	cfg.IdentityFile = ""
+	cfg.Password = "secret"
+	startAll(t, &cfg, 1)
+
+	cfg.Password = "wrong"
+	if _, _, err := sshclient.Start(t.Context(), ".", &cfg, "true"); err == nil {
+		t.Fatal("must fail")
+	}
+}
+
+func TestStartKeyboardInteractive(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	server := sshtest.NewServer(t)
+	server.SetKeyboardInteractive("secret")
+
+	cfg := server.Config
+	cfg.IdentityFile = ""
+	cfg.PasswordCommand = "echo secret"
+	startAll(t, &cfg, 1)
+}
+
+func TestStartAuthFailure(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	server := sshtest.NewServer(t)
+
+	cfg := server.Config
+	cfg.IdentityFile = filepath.Join(t.TempDir(), "nonexistent")
+	_, _, err := sshclient.Start(t.Context(), ".", &cfg, "true")
+	if err == nil {
+		t.Fatal("must fail")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "attempted methods") || !strings.Contains(msg, "identity-file") {
+		t.Fatal(err)
+	}
+}
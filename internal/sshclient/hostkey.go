@@ -0,0 +1,173 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Host key verification failure, with the key offered by the server.
+type HostKeyError struct {
+	// e.g. `example.com:22`
+	Hostname string
+	// e.g. `ssh-ed25519`
+	KeyType string
+	// e.g. `SHA256:...`
+	Fingerprint string
+
+	Err error
+}
+
+func (e *HostKeyError) Error() string {
+	return fmt.Sprintf("host key verification failed: %s %s %s: %s", e.Hostname, e.KeyType, e.Fingerprint, e.Err)
+}
+
+func (e *HostKeyError) Unwrap() error {
+	return e.Err
+}
+
+func newHostKeyError(hostname string, key ssh.PublicKey, err error) error {
+	return &HostKeyError{
+		Hostname:    hostname,
+		KeyType:     key.Type(),
+		Fingerprint: fingerprint(key),
+		Err:         err,
+	}
+}
+
+// Of the key itself for certificates.
+func fingerprint(key ssh.PublicKey) string {
+	if cert, ok := key.(*ssh.Certificate); ok {
+		key = cert.Key
+	}
+	return ssh.FingerprintSHA256(key)
+}
+
+func pinnedHostkeyCallback(fingerprints []string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if slices.Contains(fingerprints, fingerprint(key)) {
+			return nil
+		}
+		return newHostKeyError(hostname, key, errors.New("not pinned"))
+	}
+}
+
+func existingFiles(paths ...string) []string {
+	results := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			results = append(results, path)
+		}
+	}
+	return results
+}
+
+// Serializes recording to `tofu-known-hosts-file`.
+var tofuMu sync.Mutex
+
+// Record the key of the unknown host. Keys recorded by others at the same time are checked.
+func trustOnFirstUse(path string, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	tofuMu.Lock()
+	defer tofuMu.Unlock()
+
+	if len(existingFiles(path)) > 0 {
+		check, err := knownhosts.New(path)
+		if err != nil {
+			return err
+		}
+
+		err = check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	fp, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	if _, err := fmt.Fprintln(fp, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func tofuKnownHostsFile(cfgPath string, cfg *Config) (string, error) {
+	if cfg.TofuKnownHostsFile != "" {
+		return resolvePath(cfgPath, cfg.TofuKnownHostsFile), nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "seigo", "known_hosts"), nil
+}
+
+// Pinned fingerprints, or known_hosts files with `@cert-authority`, `@revoked` and hashed hostnames.
+func newHostkeyCallback(cfgPath string, cfg *Config) (ssh.HostKeyCallback, error) {
+	if len(cfg.HostKeyFingerprints) > 0 {
+		return pinnedHostkeyCallback(cfg.HostKeyFingerprints), nil
+	}
+
+	global := cfg.GlobalKnownHostsFile
+	if global == "" {
+		global = "/etc/ssh/ssh_known_hosts"
+	}
+
+	users := cfg.UserKnownHostsFile
+	if users == "" {
+		if home, err := os.UserHomeDir(); err != nil {
+			return nil, err
+		} else {
+			users = filepath.Join(home, "./.ssh/known_hosts")
+		}
+	}
+
+	files := []string{global, users}
+	tofu := ""
+	if cfg.TrustOnFirstUse {
+		path, err := tofuKnownHostsFile(cfgPath, cfg)
+		if err != nil {
+			return nil, err
+		}
+		tofu = path
+		files = append(files, tofu)
+	}
+
+	// All files at once, so that `@revoked` in one file applies to the others.
+	check, err := knownhosts.New(existingFiles(files...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := check(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if tofu != "" && errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			err = trustOnFirstUse(tofu, hostname, remote, key)
+			if err == nil {
+				return nil
+			}
+		}
+
+		return newHostKeyError(hostname, key, err)
+	}, nil
+}
@@ -0,0 +1,146 @@
+package sshclient_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient/sshtest"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func serverAddr(s *sshtest.Server) string {
+	return net.JoinHostPort(s.Config.Hostname, strconv.Itoa(int(s.Config.Port)))
+}
+
+func writeKnownHosts(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func startHostKeyError(t *testing.T, cfg *sshclient.Config) *sshclient.HostKeyError {
+	t.Helper()
+
+	_, _, err := sshclient.Start(t.Context(), ".", cfg, "true")
+	var hostKeyErr *sshclient.HostKeyError
+	if !errors.As(err, &hostKeyErr) {
+		t.Fatalf("unexpected: %v", err)
+	}
+	return hostKeyErr
+}
+
+func TestStartHostKeyFingerprints(t *testing.T) {
+	server := sshtest.NewServer(t)
+	fingerprint := ssh.FingerprintSHA256(server.HostKey)
+
+	cfg := server.Config
+	cfg.UserKnownHostsFile = writeKnownHosts(t)
+	cfg.HostKeyFingerprints = []string{fingerprint}
+	startAll(t, &cfg, 1)
+
+	cfg.HostKeyFingerprints = []string{ssh.FingerprintSHA256(newSigner(t).PublicKey())}
+	hostKeyErr := startHostKeyError(t, &cfg)
+	if hostKeyErr.Fingerprint != fingerprint {
+		t.Fatalf("%s != %s", hostKeyErr.Fingerprint, fingerprint)
+	}
+}
+
+func TestStartHostKeyKnownHosts(t *testing.T) {
+	server := sshtest.NewServer(t)
+	addr := knownhosts.Normalize(serverAddr(server))
+
+	t.Run("hashed", func(t *testing.T) {
+		cfg := server.Config
+		cfg.UserKnownHostsFile = writeKnownHosts(t, knownhosts.Line([]string{knownhosts.HashHostname(addr)}, server.HostKey))
+		startAll(t, &cfg, 1)
+	})
+
+	t.Run("revoked in another file", func(t *testing.T) {
+		cfg := server.Config
+		cfg.GlobalKnownHostsFile = writeKnownHosts(t, "@revoked * "+strings.TrimSpace(string(ssh.MarshalAuthorizedKey(server.HostKey))))
+
+		hostKeyErr := startHostKeyError(t, &cfg)
+		var revoked *knownhosts.RevokedError
+		if !errors.As(hostKeyErr, &revoked) {
+			t.Fatal(hostKeyErr)
+		}
+	})
+
+	t.Run("cert-authority", func(t *testing.T) {
+		ca := newSigner(t)
+		server := sshtest.NewServer(t)
+		server.SetHostCA(t, ca)
+
+		cfg := server.Config
+		cfg.UserKnownHostsFile = writeKnownHosts(t, fmt.Sprintf("@cert-authority %s %s", knownhosts.Normalize(serverAddr(server)), ssh.MarshalAuthorizedKey(ca.PublicKey())))
+		startAll(t, &cfg, 1)
+	})
+}
+
+func TestStartTrustOnFirstUse(t *testing.T) {
+	server := sshtest.NewServer(t)
+	tofu := filepath.Join(t.TempDir(), "seigo", "known_hosts")
+
+	cfg := server.Config
+	cfg.UserKnownHostsFile = writeKnownHosts(t)
+	cfg.TrustOnFirstUse = true
+	cfg.TofuKnownHostsFile = tofu
+	startAll(t, &cfg, 1)
+
+	b, err := os.ReadFile(tofu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wants := knownhosts.Line([]string{knownhosts.Normalize(serverAddr(server))}, server.HostKey) + "\n"
+	if string(b) != wants {
+		t.Fatalf("%q != %q", b, wants)
+	}
+
+	// Recorded key is trusted. Not recorded twice.
+	cfg.IdleTimeout = "1ns"
+	startAll(t, &cfg, 1)
+	if b, err := os.ReadFile(tofu); err != nil || string(b) != wants {
+		t.Fatalf("%q, %v", b, err)
+	}
+
+	// Changed key is rejected.
+	other := sshtest.NewServer(t)
+	cfg = other.Config
+	cfg.UserKnownHostsFile = writeKnownHosts(t)
+	cfg.TrustOnFirstUse = true
+	cfg.TofuKnownHostsFile = writeKnownHosts(t, knownhosts.Line([]string{knownhosts.Normalize(serverAddr(other))}, server.HostKey))
+
+	hostKeyErr := startHostKeyError(t, &cfg)
+	var keyErr *knownhosts.KeyError
+	if !errors.As(hostKeyErr, &keyErr) || len(keyErr.Want) != 1 {
+		t.Fatal(hostKeyErr)
+	}
+}
@@ -0,0 +1,266 @@
+package sshclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Same as the default `MaxSessions` of sshd.
+const defaultMaxSessions = 10
+
+const defaultIdleTimeout = time.Minute
+
+// Retries for broken clients and clients which reached `MaxSessions` of the server.
+const maxSessionAttempts = 3
+
+type pooledClient struct {
+	// Closed when dialed. Below two are available after that.
+	ready  chan struct{}
+	client *ssh.Client
+	err    error
+	// Closes the client.
+	close func()
+
+	// Below... Needs pool.mu.
+
+	sessions    int
+	maxSessions int
+	idle        *time.Timer
+	removed     bool
+}
+
+// Authenticated clients shared by sessions to the same host.
+type pool struct {
+	mu      sync.Mutex
+	clients map[string][]*pooledClient
+}
+
+var clients = &pool{
+	clients: make(map[string][]*pooledClient),
+}
+
+// Clients are shared only if the whole config is the same.
+func poolKey(cfgPath string, cfg *Config) (string, error) {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\x00%s", cfgPath, b), nil
+}
+
+func poolLimits(cfg *Config) (int, time.Duration, error) {
+	maxSessions := cfg.MaxSessions
+	if maxSessions <= 0 {
+		maxSessions = defaultMaxSessions
+	}
+
+	idleTimeout := defaultIdleTimeout
+	if cfg.IdleTimeout != "" {
+		d, err := time.ParseDuration(cfg.IdleTimeout)
+		if err != nil {
+			return 0, 0, fmt.Errorf("idle-timeout: %w", err)
+		}
+		idleTimeout = d
+	}
+
+	return maxSessions, idleTimeout, nil
+}
+
+// A client which has room for a session. Dial a new one if none. cx is used only for dialing and waiting for it.
+func (p *pool) acquire(cx context.Context, key, cfgPath string, cfg *Config, maxSessions int, idleTimeout time.Duration) (*pooledClient, error) {
+	for {
+		p.mu.Lock()
+		var found *pooledClient
+		for _, c := range p.clients[key] {
+			if c.sessions < c.maxSessions {
+				found = c
+				break
+			}
+		}
+
+		if found == nil {
+			break
+		}
+
+		found.sessions++
+		if found.idle != nil {
+			found.idle.Stop()
+			found.idle = nil
+		}
+		p.mu.Unlock()
+
+		// May be dialing by another.
+		select {
+		case <-found.ready:
+		case <-cx.Done():
+			p.release(key, found, idleTimeout)
+			return nil, cx.Err()
+		}
+
+		if found.err != nil {
+			p.release(key, found, idleTimeout)
+			if cx.Err() == nil && errors.Is(found.err, context.Canceled) {
+				// The one who dialed has gone. Try again.
+				continue
+			}
+			return nil, found.err
+		}
+		return found, nil
+	}
+
+	// Sessions at the same time wait for this, instead of dialing each.
+	c := &pooledClient{
+		ready:       make(chan struct{}),
+		close:       func() {},
+		sessions:    1,
+		maxSessions: maxSessions,
+	}
+	p.clients[key] = append(p.clients[key], c)
+	p.mu.Unlock()
+
+	// Not closed with cx. The client outlives the session which dialed it.
+	client, err := dial(cx, cfgPath, cfg)
+	if err != nil {
+		c.err = err
+		close(c.ready)
+		p.remove(key, c)
+		return nil, err
+	}
+
+	p.mu.Lock()
+	c.client = client
+	c.close = func() {
+		_ = client.Close()
+	}
+	close(c.ready)
+	p.mu.Unlock()
+
+	go func() {
+		// Disconnected. Sessions after this dial a new one.
+		_ = client.Wait()
+		p.remove(key, c)
+	}()
+
+	return c, nil
+}
+
+func (p *pool) release(key string, c *pooledClient, idleTimeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c.sessions--
+	if c.sessions > 0 {
+		return
+	}
+	if c.removed {
+		// The last session on a client out of the pool.
+		c.close()
+		return
+	}
+
+	c.idle = time.AfterFunc(idleTimeout, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if c.sessions == 0 {
+			p.removeLocked(key, c)
+		}
+	})
+}
+
+func (p *pool) remove(key string, c *pooledClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.removeLocked(key, c)
+}
+
+// Sessions after this use another client. Closed when no sessions remain.
+func (p *pool) removeLocked(key string, c *pooledClient) {
+	if c.removed {
+		return
+	}
+	c.removed = true
+
+	items := p.clients[key]
+	for i, item := range items {
+		if item == c {
+			items = append(items[:i], items[i+1:]...)
+			break
+		}
+	}
+	if len(items) == 0 {
+		delete(p.clients, key)
+	} else {
+		p.clients[key] = items
+	}
+
+	if c.idle != nil {
+		c.idle.Stop()
+		c.idle = nil
+	}
+	if c.sessions == 0 {
+		c.close()
+	}
+}
+
+// Reached `MaxSessions` of the server, which is less than configured.
+func (p *pool) limit(c *pooledClient) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c.sessions--
+	if c.sessions == 0 {
+		return false
+	}
+	c.maxSessions = c.sessions
+	return true
+}
+
+// A session on a pooled client. Call `release` after the session is closed.
+func (p *pool) newSession(cx context.Context, cfgPath string, cfg *Config) (session *ssh.Session, release func(), err error) {
+	key, err := poolKey(cfgPath, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	maxSessions, idleTimeout, err := poolLimits(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for range maxSessionAttempts {
+		c, err := p.acquire(cx, key, cfgPath, cfg, maxSessions, idleTimeout)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		session, err := c.client.NewSession()
+		if err == nil {
+			return session, func() {
+				p.release(key, c, idleTimeout)
+			}, nil
+		}
+
+		var openErr *ssh.OpenChannelError
+		if errors.As(err, &openErr) && openErr.Reason == ssh.Prohibited {
+			if !p.limit(c) {
+				// Not even one.
+				p.remove(key, c)
+				return nil, nil, err
+			}
+			continue
+		}
+
+		// May be broken. Reconnect, without closing the sessions of others on it.
+		p.remove(key, c)
+		p.release(key, c, idleTimeout)
+	}
+
+	return nil, nil, errors.New("no available ssh sessions")
+}
@@ -0,0 +1,222 @@
+package sshclient_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient/sshtest"
+)
+
+func startAll(t *testing.T, cfg *sshclient.Config, n int) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			stdout, wait, err := sshclient.Start(t.Context(), ".", cfg, "sleep 0.2; echo ok")
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer wait()
+
+			b, err := io.ReadAll(stdout)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if string(b) != "ok\n" {
+				t.Errorf("%q != %q", b, "ok\n")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestStartPool(t *testing.T) {
+	server := sshtest.NewServer(t)
+	cfg := server.Config
+	cfg.IdleTimeout = "200ms"
+
+	startAll(t, &cfg, 5)
+	if n := server.Connections(); n != 1 {
+		t.Fatalf("%d != 1", n)
+	}
+
+	// Reused while idle.
+	startAll(t, &cfg, 1)
+	if n := server.Connections(); n != 1 {
+		t.Fatalf("%d != 1", n)
+	}
+
+	// Closed after idle-timeout.
+	time.Sleep(500 * time.Millisecond)
+	startAll(t, &cfg, 1)
+	if n := server.Connections(); n != 2 {
+		t.Fatalf("%d != 2", n)
+	}
+
+	// Reconnected.
+	server.Disconnect()
+	startAll(t, &cfg, 1)
+	if n := server.Connections(); n != 3 {
+		t.Fatalf("%d != 3", n)
+	}
+}
+
+func TestStartPoolMaxSessions(t *testing.T) {
+	server := sshtest.NewServer(t)
+	cfg := server.Config
+	cfg.MaxSessions = 2
+
+	startAll(t, &cfg, 4)
+	if n := server.Connections(); n != 2 {
+		t.Fatalf("%d != 2", n)
+	}
+
+	// Less than configured. Learned from the rejection.
+	server = sshtest.NewServer(t)
+	server.SetMaxSessions(2)
+	cfg = server.Config
+
+	startAll(t, &cfg, 4)
+	if n := server.Connections(); n != 2 {
+		t.Fatalf("%d != 2", n)
+	}
+}
+
+func TestStartPoolSessionFailure(t *testing.T) {
+	server := sshtest.NewServer(t)
+	cfg := server.Config
+
+	stdout, wait, err := sshclient.Start(t.Context(), ".", &cfg, "sleep 0.3; echo ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wait()
+
+	// Another session fails once on the same client. Reconnected.
+	server.RejectSessions(1)
+	startAll(t, &cfg, 1)
+	if n := server.Connections(); n != 2 {
+		t.Fatalf("%d != 2", n)
+	}
+
+	// The running session is not closed with the client.
+	b, err := io.ReadAll(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "ok\n" {
+		t.Fatalf("%q != %q", b, "ok\n")
+	}
+}
+
+// Accepts connections but never speaks SSH.
+func newSilentListener(t *testing.T) uint16 {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() {
+				_ = conn.Close()
+			})
+		}
+	}()
+
+	return uint16(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func TestStartConnectTimeout(t *testing.T) {
+	server := sshtest.NewServer(t)
+	cfg := server.Config
+	cfg.Port = newSilentListener(t)
+	cfg.ConnectTimeout = "200ms"
+
+	start := time.Now()
+	_, _, err := sshclient.Start(t.Context(), ".", &cfg, "true")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatal(d)
+	}
+}
+
+func TestStartCanceledWhileDialing(t *testing.T) {
+	server := sshtest.NewServer(t)
+	cfg := server.Config
+	cfg.Port = newSilentListener(t)
+
+	errs := make(chan error, 2)
+	cx, cancel := context.WithCancel(t.Context())
+	for range 2 {
+		// The second one waits for the dial by the first one.
+		go func() {
+			_, _, err := sshclient.Start(cx, ".", &cfg, "true")
+			errs <- err
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	for range 2 {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("unexpected: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("not aborted")
+		}
+	}
+}
+
+func TestStartPoolOutlivesContext(t *testing.T) {
+	server := sshtest.NewServer(t)
+	cfg := server.Config
+
+	cx, cancel := context.WithCancel(t.Context())
+	stdout, wait, err := sshclient.Start(cx, ".", &cfg, "echo ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(stdout); err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	cancel()
+
+	// Not closed with the context of the session which dialed it.
+	startAll(t, &cfg, 1)
+	if n := server.Connections(); n != 1 {
+		t.Fatalf("%d != 1", n)
+	}
+}
@@ -0,0 +1,188 @@
+package sshclient_test
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient/sshtest"
+)
+
+func pipe(a, b net.Conn) {
+	defer a.Close()
+	defer b.Close()
+
+	go func() {
+		_, _ = io.Copy(a, b)
+	}()
+	_, _ = io.Copy(b, a)
+}
+
+func TestStartProxyJump(t *testing.T) {
+	bastion1 := sshtest.NewServer(t)
+	bastion2 := sshtest.NewServer(t)
+	server := sshtest.NewServer(t)
+
+	cfg := server.Config
+	cfg.ProxyJump = []sshclient.Config{bastion1.Config, bastion2.Config}
+
+	startAll(t, &cfg, 2)
+	for _, s := range []*sshtest.Server{bastion1, bastion2, server} {
+		if n := s.Connections(); n != 1 {
+			t.Fatalf("%d != 1", n)
+		}
+	}
+}
+
+func TestStartProxyJumpUnknownHost(t *testing.T) {
+	bastion := sshtest.NewServer(t)
+	server := sshtest.NewServer(t)
+
+	cfg := server.Config
+	cfg.ProxyJump = []sshclient.Config{bastion.Config}
+	// Known hosts of the bastion are not for the target.
+	cfg.UserKnownHostsFile = bastion.Config.UserKnownHostsFile
+
+	if _, _, err := sshclient.Start(t.Context(), ".", &cfg, "true"); err == nil {
+		t.Fatal("must fail")
+	}
+	if n := server.Connections(); n != 0 {
+		t.Fatalf("%d != 0", n)
+	}
+}
+
+func TestStartHttpProxy(t *testing.T) {
+	var requests atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		requests.Add(1)
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			_ = upstream.Close()
+			return
+		}
+		if _, err := conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+			_ = conn.Close()
+			_ = upstream.Close()
+			return
+		}
+		pipe(conn, upstream)
+	}))
+	t.Cleanup(proxy.Close)
+
+	server := sshtest.NewServer(t)
+	cfg := server.Config
+	cfg.Proxy = "http://user:pass@" + proxy.Listener.Addr().String()
+
+	startAll(t, &cfg, 1)
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("%d != 1", n)
+	}
+}
+
+// No authentication, CONNECT with an IPv4 address only.
+func serveSocks5(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	var requests atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				// https://datatracker.ietf.org/doc/html/rfc1928
+				head := make([]byte, 2)
+				if _, err := io.ReadFull(conn, head); err != nil {
+					_ = conn.Close()
+					return
+				}
+				if _, err := io.ReadFull(conn, make([]byte, head[1])); err != nil {
+					_ = conn.Close()
+					return
+				}
+				if _, err := conn.Write([]byte{5, 0}); err != nil {
+					_ = conn.Close()
+					return
+				}
+
+				req := make([]byte, 10)
+				if _, err := io.ReadFull(conn, req); err != nil || req[3] != 1 {
+					_ = conn.Close()
+					return
+				}
+				addr := net.JoinHostPort(net.IP(req[4:8]).String(), strconv.Itoa(int(binary.BigEndian.Uint16(req[8:]))))
+				requests.Add(1)
+
+				upstream, err := net.Dial("tcp", addr)
+				if err != nil {
+					_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					_ = conn.Close()
+					return
+				}
+				if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+					_ = conn.Close()
+					_ = upstream.Close()
+					return
+				}
+				pipe(conn, upstream)
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), &requests
+}
+
+func TestStartSocks5Proxy(t *testing.T) {
+	addr, requests := serveSocks5(t)
+
+	bastion := sshtest.NewServer(t)
+	server := sshtest.NewServer(t)
+	cfg := server.Config
+	cfg.ProxyJump = []sshclient.Config{bastion.Config}
+	cfg.Proxy = "socks5://" + addr
+
+	startAll(t, &cfg, 1)
+	// Only to the first hop.
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("%d != 1", n)
+	}
+	if n := server.Connections(); n != 1 {
+		t.Fatalf("%d != 1", n)
+	}
+}
+
+func TestStartUnsupportedProxy(t *testing.T) {
+	server := sshtest.NewServer(t)
+	cfg := server.Config
+	cfg.Proxy = "ftp://127.0.0.1"
+
+	if _, _, err := sshclient.Start(t.Context(), ".", &cfg, "true"); err == nil {
+		t.Fatal("must fail")
+	}
+}
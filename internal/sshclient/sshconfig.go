@@ -0,0 +1,385 @@
+package sshclient
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Same as OpenSSH.
+const maxIncludeDepth = 16
+
+// Lines after `Host`. nil for lines before any `Host`.
+type sshConfigBlock struct {
+	patterns []string
+	// `Match` is not supported. Never matches.
+	never bool
+}
+
+func matchPattern(pattern, text string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := 0; i <= len(text); i++ {
+				if matchPattern(pattern[1:], text[i:]) {
+					return true
+				}
+			}
+			return false
+
+		case '?':
+			if len(text) == 0 {
+				return false
+			}
+
+		default:
+			if len(text) == 0 || pattern[0] != text[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		text = text[1:]
+	}
+	return len(text) == 0
+}
+
+func (b *sshConfigBlock) match(host string) bool {
+	if b == nil {
+		return true
+	}
+	if b.never {
+		return false
+	}
+
+	host = strings.ToLower(host)
+	matched := false
+	for _, pattern := range b.patterns {
+		pattern = strings.ToLower(pattern)
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			if matchPattern(negated, host) {
+				return false
+			}
+			continue
+		}
+		if matchPattern(pattern, host) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+type sshConfigEntry struct {
+	block *sshConfigBlock
+	// Lower case
+	key  string
+	args []string
+}
+
+// OpenSSH client config. e.g. `~/.ssh/config`
+type sshConfig struct {
+	entries []sshConfigEntry
+}
+
+// Split `Key value`, `Key=value` and double quoted arguments.
+func splitSshConfigLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil, nil
+	}
+	key := strings.ToLower(line[:end])
+	rest := strings.TrimLeft(line[end:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+
+	args := make([]string, 0)
+	var b strings.Builder
+	quoted, inArg := false, false
+	for _, r := range rest {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+		default:
+			b.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quoted {
+		return "", nil, fmt.Errorf("unterminated quote: %s", line)
+	}
+	if inArg {
+		args = append(args, b.String())
+	}
+
+	return key, args, nil
+}
+
+func (c *sshConfig) parseFile(path string, block *sshConfigBlock, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("too deep include: %s", path)
+	}
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	scanner := bufio.NewScanner(fp)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+
+		key, args, err := splitSshConfigLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineno, err)
+		}
+
+		switch key {
+		case "":
+			continue
+
+		case "host":
+			block = &sshConfigBlock{patterns: args}
+
+		case "match":
+			block = &sshConfigBlock{never: true}
+
+		case "include":
+			for _, arg := range args {
+				pattern := expandHome(arg)
+				if !filepath.IsAbs(pattern) {
+					// Same as OpenSSH, for the user config.
+					pattern = filepath.Join(filepath.Dir(path), pattern)
+				}
+
+				matches, err := filepath.Glob(pattern)
+				if err != nil {
+					return fmt.Errorf("%s:%d: %w", path, lineno, err)
+				}
+				for _, match := range matches {
+					if err := c.parseFile(match, block, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+
+		default:
+			c.entries = append(c.entries, sshConfigEntry{
+				block: block,
+				key:   key,
+				args:  args,
+			})
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Missing file is same as empty.
+func loadSshConfig(path string) (*sshConfig, error) {
+	c := &sshConfig{}
+	if path == "none" {
+		return c, nil
+	}
+
+	if path == "" {
+		path = "~/.ssh/config"
+	}
+	path = expandHome(path)
+
+	if err := c.parseFile(path, nil, 0); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &sshConfig{}, nil
+		}
+		return nil, fmt.Errorf("ssh-config-file: %w", err)
+	}
+	return c, nil
+}
+
+// The first value for host. Same as OpenSSH.
+func (c *sshConfig) lookup(host string, key string) []string {
+	for _, entry := range c.entries {
+		if entry.key == key && len(entry.args) > 0 && entry.block.match(host) {
+			return entry.args
+		}
+	}
+	return nil
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// `%h`, `%n`, `%p`, `%r`, `%u`, `%d`, `%%` and `~`. Relative paths are from the home directory.
+func expandSshConfigPath(path string, alias string, cfg *Config) string {
+	home, _ := os.UserHomeDir()
+	local := ""
+	if u, err := user.Current(); err == nil {
+		local = u.Username
+	}
+	remote := cfg.Username
+	if remote == "" {
+		remote = local
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 22
+	}
+
+	r := strings.NewReplacer(
+		"%%", "%",
+		"%h", cfg.Hostname,
+		"%n", alias,
+		"%p", strconv.Itoa(int(port)),
+		"%r", remote,
+		"%u", local,
+		"%d", home,
+	)
+	path = expandHome(r.Replace(path))
+	if !filepath.IsAbs(path) && home != "" {
+		path = filepath.Join(home, path)
+	}
+	return path
+}
+
+// `[user@]host[:port]` or `ssh://[user@]host[:port]`, separated with comma.
+func parseProxyJump(value string) ([]Config, error) {
+	if value == "none" {
+		return nil, nil
+	}
+
+	hops := make([]Config, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimPrefix(item, "ssh://")
+
+		var hop Config
+		if username, rest, ok := strings.Cut(item, "@"); ok {
+			hop.Username = username
+			item = rest
+		}
+
+		host, port := item, ""
+		if strings.HasPrefix(item, "[") {
+			// IPv6
+			end := strings.Index(item, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("ProxyJump: %s", value)
+			}
+			host = item[1:end]
+			if p, ok := strings.CutPrefix(item[end+1:], ":"); ok {
+				port = p
+			} else if item[end+1:] != "" {
+				return nil, fmt.Errorf("ProxyJump: %s", value)
+			}
+		} else if h, p, ok := strings.Cut(item, ":"); ok {
+			host, port = h, p
+		}
+		if port != "" {
+			n, err := strconv.ParseUint(port, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("ProxyJump: %w", err)
+			}
+			hop.Port = uint16(n)
+		}
+		if host == "" {
+			return nil, fmt.Errorf("ProxyJump: %s", value)
+		}
+		hop.Hostname = host
+
+		hops = append(hops, hop)
+	}
+	return hops, nil
+}
+
+// Fill the unset fields from the ssh config. `hostname` is used as `Host` of the ssh config.
+func (c *sshConfig) apply(cfg *Config) (*Config, error) {
+	result := *cfg
+	alias := cfg.Hostname
+	if alias == "" {
+		return &result, nil
+	}
+
+	first := func(key string) string {
+		if args := c.lookup(alias, key); len(args) > 0 {
+			return args[0]
+		}
+		return ""
+	}
+
+	if v := first("hostname"); v != "" {
+		result.Hostname = strings.ReplaceAll(v, "%h", alias)
+	}
+	if v := first("port"); v != "" && result.Port == 0 {
+		port, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("Port: %w", err)
+		}
+		result.Port = uint16(port)
+	}
+	if v := first("user"); v != "" && result.Username == "" {
+		result.Username = v
+	}
+	if v := first("identityfile"); v != "" && v != "none" && result.IdentityFile == "" {
+		result.IdentityFile = expandSshConfigPath(v, alias, &result)
+	}
+	if v := first("certificatefile"); v != "" && v != "none" && result.CertificateFile == "" {
+		result.CertificateFile = expandSshConfigPath(v, alias, &result)
+	}
+	if v := first("identityagent"); result.IdentityAgent == "" {
+		switch {
+		case v == "" || v == "none" || v == "SSH_AUTH_SOCK":
+		case strings.HasPrefix(v, "$"):
+			result.IdentityAgent = os.Getenv(v[1:])
+		default:
+			result.IdentityAgent = expandSshConfigPath(v, alias, &result)
+		}
+	}
+	if v := first("globalknownhostsfile"); v != "" && v != "none" && result.GlobalKnownHostsFile == "" {
+		result.GlobalKnownHostsFile = expandSshConfigPath(v, alias, &result)
+	}
+	if v := first("userknownhostsfile"); v != "" && v != "none" && result.UserKnownHostsFile == "" {
+		result.UserKnownHostsFile = expandSshConfigPath(v, alias, &result)
+	}
+	if v := first("hostkeyalgorithms"); v != "" && result.HostKeyAlgorithms == nil {
+		// Modifiers of the defaults are not supported.
+		if !strings.ContainsAny(v[:1], "+-^") {
+			result.HostKeyAlgorithms = strings.Split(v, ",")
+		}
+	}
+	if v := first("connecttimeout"); v != "" && result.ConnectTimeout == "" {
+		if _, err := strconv.ParseUint(v, 10, 32); err != nil {
+			return nil, fmt.Errorf("ConnectTimeout: %w", err)
+		}
+		result.ConnectTimeout = v + "s"
+	}
+	if v := first("proxyjump"); v != "" && len(result.ProxyJump) == 0 {
+		hops, err := parseProxyJump(v)
+		if err != nil {
+			return nil, err
+		}
+		result.ProxyJump = hops
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,92 @@
+package sshclient_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient"
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient/sshtest"
+)
+
+func writeSshConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func hostSection(alias string, s *sshtest.Server) string {
+	return fmt.Sprintf(`Host %s
+    HostName %s
+    Port=%d
+    User "%s"
+    IdentityFile %s
+    UserKnownHostsFile %s
+    ConnectTimeout 10
+`, alias, s.Config.Hostname, s.Config.Port, s.Config.Username, s.Config.IdentityFile, s.Config.UserKnownHostsFile)
+}
+
+func TestStartSshConfig(t *testing.T) {
+	server := sshtest.NewServer(t)
+	dir := t.TempDir()
+
+	path := writeSshConfig(t, dir, "config", `# comment
+Host !*.example.com app*
+    IdentityFile /nonexistent
+`+hostSection("*.example.com", server))
+
+	cfg := &sshclient.Config{
+		Hostname:      "app.example.com",
+		SshConfigFile: path,
+	}
+	startAll(t, cfg, 1)
+
+	// Explicit fields are prior.
+	cfg = &sshclient.Config{
+		Hostname:      "app2.example.com",
+		Username:      "nobody",
+		SshConfigFile: path,
+	}
+	if _, _, err := sshclient.Start(t.Context(), ".", cfg, "true"); err == nil {
+		t.Fatal("must fail")
+	}
+
+	// Not matched. The first value is used.
+	cfg = &sshclient.Config{
+		Hostname:      "app",
+		SshConfigFile: path,
+	}
+	if _, _, err := sshclient.Start(t.Context(), ".", cfg, "true"); err == nil {
+		t.Fatal("must fail")
+	}
+}
+
+func TestStartSshConfigInclude(t *testing.T) {
+	bastion := sshtest.NewServer(t)
+	server := sshtest.NewServer(t)
+	dir := t.TempDir()
+
+	if err := os.Mkdir(filepath.Join(dir, "config.d"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeSshConfig(t, dir, "config.d/bastion", hostSection("bastion", bastion))
+	writeSshConfig(t, dir, "config.d/target", hostSection("target", server)+"    ProxyJump bastion\n")
+	path := writeSshConfig(t, dir, "config", "Include config.d/*\n")
+
+	cfg := &sshclient.Config{
+		Hostname:      "target",
+		SshConfigFile: path,
+	}
+	startAll(t, cfg, 1)
+	if n := bastion.Connections(); n != 1 {
+		t.Fatalf("%d != 1", n)
+	}
+	if n := server.Connections(); n != 1 {
+		t.Fatalf("%d != 1", n)
+	}
+}
@@ -0,0 +1,378 @@
+// Package sshtest provides an SSH server for tests, which runs exec requests with the local `sh`.
+package sshtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ysuzuki-bysystems/seigo/internal/sshclient"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+type Server struct {
+	// Client configuration to connect to this server.
+	Config sshclient.Config
+	// Host key without certificate.
+	HostKey ssh.PublicKey
+
+	hostSigner ssh.Signer
+
+	sshConfig *ssh.ServerConfig
+
+	connections atomic.Int32
+	maxSessions atomic.Int32
+	rejects     atomic.Int32
+	conns       sync.Map
+
+	mu                  sync.Mutex
+	password            string
+	keyboardInteractive string
+	userCA              ssh.PublicKey
+}
+
+// Accept the password authentication.
+func (s *Server) SetPassword(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.password = password
+}
+
+// Accept the keyboard-interactive authentication, which asks the password.
+func (s *Server) SetKeyboardInteractive(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyboardInteractive = password
+}
+
+// Offer the host certificate signed by the CA, for the hostname of `Config`.
+func (s *Server) SetHostCA(t testing.TB, ca ssh.Signer) {
+	t.Helper()
+
+	cert := &ssh.Certificate{
+		Key:             s.HostKey,
+		CertType:        ssh.HostCert,
+		KeyId:           "test",
+		ValidPrincipals: []string{s.Config.Hostname},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewCertSigner(cert, s.hostSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sshConfig.AddHostKey(signer)
+}
+
+// Accept user certificates signed by the CA.
+func (s *Server) SetUserCA(ca ssh.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userCA = ca
+}
+
+// Number of accepted connections.
+func (s *Server) Connections() int {
+	return int(s.connections.Load())
+}
+
+// Close all connections, like a server restart.
+func (s *Server) Disconnect() {
+	s.conns.Range(func(key, _ any) bool {
+		_ = key.(net.Conn).Close()
+		return true
+	})
+}
+
+// Sessions per connection, like MaxSessions of sshd. 0 for unlimited.
+func (s *Server) SetMaxSessions(n int) {
+	s.maxSessions.Store(int32(n))
+}
+
+// Reject the next n sessions, like a transient failure of the server.
+func (s *Server) RejectSessions(n int) {
+	s.rejects.Store(int32(n))
+}
+
+func (s *Server) reject() bool {
+	for {
+		n := s.rejects.Load()
+		if n <= 0 {
+			return false
+		}
+		if s.rejects.CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
+
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	clientPublicKey, clientPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, hostPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorized, err := ssh.NewPublicKey(clientPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{}
+	certChecker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.userCA != nil && bytes.Equal(auth.Marshal(), s.userCA.Marshal())
+		},
+	}
+	sshConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if _, ok := key.(*ssh.Certificate); ok && conn.User() == "test" {
+				return certChecker.Authenticate(conn, key)
+			}
+			if conn.User() != "test" || !bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, errors.New("Unknown public key")
+			}
+			return &ssh.Permissions{}, nil
+		},
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if conn.User() != "test" || s.password == "" || string(password) != s.password {
+				return nil, errors.New("Wrong password")
+			}
+			return &ssh.Permissions{}, nil
+		},
+		KeyboardInteractiveCallback: func(conn ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			s.mu.Lock()
+			password := s.keyboardInteractive
+			s.mu.Unlock()
+			if conn.User() != "test" || password == "" {
+				return nil, errors.New("Not allowed")
+			}
+
+			answers, err := client("", "", []string{"Password: "}, []bool{false})
+			if err != nil {
+				return nil, err
+			}
+			if len(answers) != 1 || answers[0] != password {
+				return nil, errors.New("Wrong password")
+			}
+			return &ssh.Permissions{}, nil
+		},
+	}
+	sshConfig.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	addr := listener.Addr().(*net.TCPAddr)
+
+	tmpdir := t.TempDir()
+	kh := filepath.Join(tmpdir, "known_hosts")
+	if err := os.WriteFile(kh, []byte(knownhosts.Line([]string{addr.String()}, hostSigner.PublicKey())), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pemData, err := ssh.MarshalPrivateKey(clientPrivateKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ident := filepath.Join(tmpdir, "identity")
+	if err := os.WriteFile(ident, pem.EncodeToMemory(pemData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Config = sshclient.Config{
+		Hostname:           addr.IP.String(),
+		Port:               uint16(addr.Port),
+		Username:           "test",
+		IdentityFile:       ident,
+		UserKnownHostsFile: kh,
+	}
+	s.sshConfig = sshConfig
+	s.HostKey = hostSigner.PublicKey()
+	s.hostSigner = hostSigner
+
+	go func() {
+		for {
+			nconn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(nconn)
+		}
+	}()
+
+	return s
+}
+
+func (s *Server) serve(nconn net.Conn) {
+	defer nconn.Close()
+	s.conns.Store(nconn, nil)
+	defer s.conns.Delete(nconn)
+
+	s.mu.Lock()
+	sshConfig := *s.sshConfig
+	s.mu.Unlock()
+
+	conn, chans, reqs, err := ssh.NewServerConn(nconn, &sshConfig)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	s.connections.Add(1)
+
+	go ssh.DiscardRequests(reqs)
+
+	var sessions atomic.Int32
+	for newChannel := range chans {
+		if newChannel.ChannelType() == "direct-tcpip" {
+			go directTcpip(newChannel)
+			continue
+		}
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown")
+			continue
+		}
+		if s.reject() {
+			_ = newChannel.Reject(ssh.ResourceShortage, "resource shortage")
+			continue
+		}
+		if max := s.maxSessions.Load(); max > 0 && sessions.Load() >= max {
+			_ = newChannel.Reject(ssh.Prohibited, "open failed")
+			continue
+		}
+
+		channel, reqs, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		sessions.Add(1)
+		go session(channel, reqs, func() {
+			sessions.Add(-1)
+		})
+	}
+}
+
+// Port forwarding for jump hosts.
+func directTcpip(newChannel ssh.NewChannel) {
+	// https://datatracker.ietf.org/doc/html/rfc4254#section-7.2
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	defer conn.Close()
+
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	go ssh.DiscardRequests(reqs)
+
+	go func() {
+		_, _ = io.Copy(conn, channel)
+		_ = conn.(*net.TCPConn).CloseWrite()
+	}()
+	_, _ = io.Copy(channel, conn)
+}
+
+// done is called before the channel is closed.
+func session(channel ssh.Channel, reqs <-chan *ssh.Request, done func()) {
+	defer channel.Close()
+	defer done()
+
+	cx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var command string
+	for req := range reqs {
+		if req.Type != "exec" {
+			_ = req.Reply(false, nil)
+			continue
+		}
+
+		// https://datatracker.ietf.org/doc/html/rfc4254#section-6.5
+		var payload struct {
+			Command string
+		}
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			_ = req.Reply(false, nil)
+			return
+		}
+		_ = req.Reply(true, nil)
+		command = payload.Command
+		break
+	}
+
+	go func() {
+		// Closed by the client.
+		for req := range reqs {
+			_ = req.Reply(false, nil)
+		}
+		cancel()
+	}()
+
+	cmd := exec.CommandContext(cx, "sh", "-c", command)
+	cmd.Stdout = channel
+	cmd.Stderr = channel.Stderr()
+
+	var status uint32
+	if err := cmd.Run(); err != nil {
+		status = 1
+		if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() > 0 {
+			status = uint32(exit.ExitCode())
+		}
+	}
+
+	exitdata := ssh.Marshal(struct {
+		Status uint32
+	}{
+		Status: status,
+	})
+	_, _ = channel.SendRequest("exit-status", false, exitdata)
+}
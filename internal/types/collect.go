@@ -5,4 +5,19 @@ import "time"
 type CollectOpts struct {
 	Tail  bool
 	Since time.Time
+	// Zero for no limit. Ignored in tail mode.
+	Until time.Time
+
+	// Resume after the record of this cursor. Empty to start normally.
+	After string
+	// Called with the cursor of each record just before it is yielded, by datasources which can resume.
+	// nil if not needed.
+	OnCursor func(cursor string)
+}
+
+// Reports cursor if the caller needs it.
+func (o *CollectOpts) Cursor(cursor string) {
+	if o.OnCursor != nil && cursor != "" {
+		o.OnCursor(cursor)
+	}
 }
@@ -1,3 +1,5 @@
+import * as v from "valibot";
+
 export type CollectOpts = {
   name: string;
   since?: Date;
@@ -6,14 +8,64 @@ export type CollectOpts = {
 
 type EventSourceShape = Pick<
   globalThis.EventSource,
-  "addEventListener" | "close"
+  "addEventListener" | "close" | "readyState"
 >;
 
+// EventSource.CONNECTING
+const CONNECTING = 0;
+
 type CollectOptsInternal = CollectOpts & {
   origin?: typeof globalThis.origin;
   eventSourceClass?: new (url: URL) => EventSourceShape;
 };
 
+// `error` event from the API.
+const vErrorResponse = v.object({
+  error: v.string(),
+});
+
+// The offered key is to be verified by the user.
+const vHostKeyErrorResponse = v.object({
+  error: v.string(),
+  hostname: v.string(),
+  keyType: v.string(),
+  fingerprint: v.string(),
+});
+
+export class HostKeyError extends Error {
+  hostname: string;
+  keyType: string;
+  fingerprint: string;
+
+  constructor(resp: v.InferOutput<typeof vHostKeyErrorResponse>) {
+    // With the hostname and the fingerprint.
+    super(resp.error);
+    this.name = "HostKeyError";
+    this.hostname = resp.hostname;
+    this.keyType = resp.keyType;
+    this.fingerprint = resp.fingerprint;
+  }
+}
+
+function failure(data: string): Error {
+  let json: unknown;
+  try {
+    json = JSON.parse(data);
+  } catch {
+    return new Error("Connection failure.");
+  }
+
+  const hostKeyError = v.safeParse(vHostKeyErrorResponse, json);
+  if (hostKeyError.success) {
+    return new HostKeyError(hostKeyError.output);
+  }
+  const error = v.safeParse(vErrorResponse, json);
+  if (error.success) {
+    return new Error(error.output.error);
+  }
+  return new Error("Connection failure.");
+}
+
 class EventSourceStream extends ReadableStream<string> {
   constructor(source: EventSourceShape, signal?: AbortSignal) {
     super({
@@ -25,9 +77,19 @@ class EventSourceStream extends ReadableStream<string> {
           controller.error(signal.reason),
         );
 
-        source.addEventListener("error", () =>
-          controller.error(new Error("Connection failure.")),
-        );
+        source.addEventListener("error", (event) => {
+          if (event instanceof MessageEvent) {
+            // Sent by the API with the reason. Not to reconnect.
+            source.close();
+            controller.error(failure(event.data));
+            return;
+          }
+          if (source.readyState === CONNECTING) {
+            // Reconnecting. Resumed by `Last-Event-ID`.
+            return;
+          }
+          controller.error(new Error("Connection failure."));
+        });
 
         // FIXME Possible overflow...
         source.addEventListener("message", (event) =>
@@ -92,6 +154,7 @@ if (import.meta.vitest) {
     type DummyEventSourceNotify = {
       url?: URL | undefined;
       closed?: boolean;
+      readyState?: number;
     };
 
     function newDummyEventSource(
@@ -102,6 +165,8 @@ if (import.meta.vitest) {
         extends EventTarget
         implements EventSourceShape
       {
+        readyState = notify.readyState ?? 2;
+
         constructor(url: URL) {
           super();
           notify.url = url;
@@ -186,6 +251,93 @@ if (import.meta.vitest) {
       await expect(iter.next()).rejects.toThrowError("Connection failure.");
     });
 
+    it("error event", async ({ expect }) => {
+      const abort = new AbortController();
+      setTimeout(() => abort.abort(), 1000);
+
+      const notify: DummyEventSourceNotify = { readyState: CONNECTING };
+      const DummyEventSource = newDummyEventSource(notify, [
+        new MessageEvent("error", {
+          data: JSON.stringify({ error: "bad request." }),
+        }),
+      ]);
+
+      const opts: CollectOptsInternal = {
+        name: "test",
+        tail: true,
+        since: new Date(0),
+
+        origin: "http://example.com",
+        eventSourceClass: DummyEventSource,
+      };
+
+      const iter = collect(opts, abort.signal)[Symbol.asyncIterator]();
+      await expect(iter.next()).rejects.toThrowError("bad request.");
+      expect(notify.closed).toBe(true);
+    });
+
+    it("host key error", async ({ expect }) => {
+      const abort = new AbortController();
+      setTimeout(() => abort.abort(), 1000);
+
+      const notify: DummyEventSourceNotify = { readyState: CONNECTING };
+      const DummyEventSource = newDummyEventSource(notify, [
+        new MessageEvent("error", {
+          data: JSON.stringify({
+            error:
+              "host key verification failed: example.com:22 ssh-ed25519 SHA256:xxx: not pinned",
+            hostname: "example.com:22",
+            keyType: "ssh-ed25519",
+            fingerprint: "SHA256:xxx",
+          }),
+        }),
+      ]);
+
+      const opts: CollectOptsInternal = {
+        name: "test",
+        tail: true,
+        since: new Date(0),
+
+        origin: "http://example.com",
+        eventSourceClass: DummyEventSource,
+      };
+
+      const iter = collect(opts, abort.signal)[Symbol.asyncIterator]();
+      const err = await iter.next().catch((e) => e);
+      expect(err).toBeInstanceOf(HostKeyError);
+      expect(err.fingerprint).toBe("SHA256:xxx");
+      expect(err.message).toContain("SHA256:xxx");
+    });
+
+    it("reconnecting", async ({ expect }) => {
+      const abort = new AbortController();
+      setTimeout(() => abort.abort(), 1000);
+
+      const notify: DummyEventSourceNotify = { readyState: CONNECTING };
+      const DummyEventSource = newDummyEventSource(notify, [
+        new MessageEvent("message", { data: "a" }),
+        new Event("error", {}),
+        new MessageEvent("message", { data: "b" }),
+        new MessageEvent("eof", {}),
+      ]);
+
+      const opts: CollectOptsInternal = {
+        name: "test",
+        tail: true,
+        since: new Date(0),
+
+        origin: "http://example.com",
+        eventSourceClass: DummyEventSource,
+      };
+
+      const recv: string[] = [];
+      for await (const m of collect(opts, abort.signal)) {
+        recv.push(m);
+      }
+
+      expect(recv).toEqual(["a", "b"]);
+    });
+
     it("aborted immediate", async ({ expect }) => {
       const abort = new AbortController();
       abort.abort();